@@ -0,0 +1,452 @@
+// Package disasm produces dis style listings of decoded code objects.
+package disasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"pyinstxtractor-go/marshal"
+)
+
+var ErrUnsupportedVersion = errors.New("unsupported python version")
+
+const (
+	opnameWidth = 20
+	opargWidth  = 5
+)
+
+// maxCodeDepth bounds the nesting of code objects, well above what the
+// compiler's recursion limit allows
+const maxCodeDepth = 200
+
+var cmpOp = []string{"<", "<=", "==", "!=", ">", ">=", "in", "not in", "is", "is not", "exception match", "BAD"}
+
+var nbOps = []string{
+	"+", "&", "//", "<<", "@", "*", "%", "|", "**", ">>", "-", "/", "^",
+	"+=", "&=", "//=", "<<=", "@=", "*=", "%=", "|=", "**=", ">>=", "-=", "/=", "^=",
+}
+
+var formatValueConverters = []string{"", "str", "repr", "ascii"}
+
+var functionFlags = []string{"defaults", "kwdefaults", "annotations", "closure"}
+
+var intrinsic1 = []string{
+	"INTRINSIC_1_INVALID", "INTRINSIC_PRINT", "INTRINSIC_IMPORT_STAR", "INTRINSIC_STOPITERATION_ERROR",
+	"INTRINSIC_ASYNC_GEN_WRAP", "INTRINSIC_UNARY_POSITIVE", "INTRINSIC_LIST_TO_TUPLE", "INTRINSIC_TYPEVAR",
+	"INTRINSIC_PARAMSPEC", "INTRINSIC_TYPEVARTUPLE", "INTRINSIC_SUBSCRIPT_GENERIC", "INTRINSIC_TYPEALIAS",
+}
+
+var intrinsic2 = []string{
+	"INTRINSIC_2_INVALID", "INTRINSIC_PREP_RERAISE_STAR", "INTRINSIC_TYPEVAR_WITH_BOUND",
+	"INTRINSIC_TYPEVAR_WITH_CONSTRAINTS", "INTRINSIC_SET_FUNCTION_TYPE_PARAMS", "INTRINSIC_SET_TYPEPARAM_DEFAULT",
+}
+
+// Instruction is a single decoded bytecode instruction. Inline cache entries
+// are not reported.
+type Instruction struct {
+	Offset  int
+	Opcode  byte
+	Name    string
+	HasArg  bool
+	Arg     int    // argument including any EXTENDED_ARG prefixes
+	ArgRepr string // resolved argument, e.g. the constant or name referenced
+	Line    int    // source line starting at this instruction
+	NewLine bool   // whether Line is set
+	Target  int    // jump target offset, or -1
+	IsLabel bool   // whether some instruction jumps here
+}
+
+// ExceptionEntry is an entry of the exception table used from Python 3.11
+type ExceptionEntry struct {
+	Start, End, Target, Depth int
+	Lasti                     bool
+}
+
+func opcodesFor(major, minor int) (*opcodeTable, error) {
+	if major == 2 && minor == 7 {
+		return &python27, nil
+	}
+	if major == 3 {
+		tables := []*opcodeTable{
+			&python30, &python31, &python32, &python33, &python34, &python35, &python36,
+			&python37, &python38, &python39, &python310, &python311, &python312, &python313,
+		}
+		if minor >= 0 && minor < len(tables) {
+			return tables[minor], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d.%d", ErrUnsupportedVersion, major, minor)
+}
+
+type decoder struct {
+	code         *marshal.PyCodeObject
+	ops          *opcodeTable
+	major, minor int
+}
+
+func newDecoder(code *marshal.PyCodeObject) (*decoder, error) {
+	major, minor := code.GetPythonVersion()
+	ops, err := opcodesFor(major, minor)
+	if err != nil {
+		return nil, err
+	}
+	return &decoder{code: code, ops: ops, major: major, minor: minor}, nil
+}
+
+func (d *decoder) atLeast(major, minor int) bool {
+	return d.major > major || (d.major == major && d.minor >= minor)
+}
+
+// Instructions decodes the bytecode of code. Decoding stops at a truncated
+// trailing instruction.
+func Instructions(code *marshal.PyCodeObject) ([]Instruction, error) {
+	d, err := newDecoder(code)
+	if err != nil {
+		return nil, err
+	}
+	return d.instructions(true), nil
+}
+
+// RawInstructions decodes the bytecode of code like Instructions, but leaves
+// ArgRepr empty so that no constant is formatted.
+func RawInstructions(code *marshal.PyCodeObject) ([]Instruction, error) {
+	d, err := newDecoder(code)
+	if err != nil {
+		return nil, err
+	}
+	return d.instructions(false), nil
+}
+
+func (d *decoder) instructions(resolve bool) []Instruction {
+	bytecode := d.code.GetBytecode()
+	wordcode := d.atLeast(3, 6)
+	lines := d.lineStarts()
+
+	var result []Instruction
+	extendedArg := 0
+	for offset := 0; offset < len(bytecode); {
+		op := bytecode[offset]
+		def := d.ops[op]
+		inst := Instruction{Offset: offset, Opcode: op, Name: def.name, Target: -1}
+		inst.Line, inst.NewLine = lines[offset]
+		if inst.Name == "" {
+			inst.Name = "<" + strconv.Itoa(int(op)) + ">"
+		}
+		inst.HasArg = def.arg != argNone || (def.name == "" && op >= 90 && !d.atLeast(3, 13))
+
+		size := 1
+		if wordcode {
+			if offset+2 > len(bytecode) {
+				break
+			}
+			size = 2
+			if inst.HasArg {
+				inst.Arg = int(bytecode[offset+1]) | extendedArg
+			}
+			extendedArg = 0
+			if def.name == "EXTENDED_ARG" {
+				extendedArg = inst.Arg << 8
+			}
+		} else if inst.HasArg {
+			if offset+3 > len(bytecode) {
+				break
+			}
+			size = 3
+			inst.Arg = int(bytecode[offset+1]) | int(bytecode[offset+2])<<8 | extendedArg
+			extendedArg = 0
+			if def.name == "EXTENDED_ARG" {
+				extendedArg = inst.Arg << 16
+			}
+		}
+
+		next := offset + size + 2*def.caches
+		if inst.HasArg {
+			inst.Target = d.jumpTarget(def, inst.Arg, next)
+			if resolve {
+				inst.ArgRepr = d.argRepr(def, inst.Arg, inst.Target)
+			}
+		}
+		result = append(result, inst)
+		offset = next
+	}
+
+	labels := make(map[int]bool)
+	for _, inst := range result {
+		if inst.Target >= 0 {
+			labels[inst.Target] = true
+		}
+	}
+	for _, entry := range d.exceptionTable() {
+		labels[entry.Target] = true
+	}
+	for i := range result {
+		result[i].IsLabel = labels[result[i].Offset]
+	}
+	return result
+}
+
+// jumpTarget returns the offset a jump instruction continues at, or -1 for
+// other instructions. next is the offset following the instruction and its
+// caches.
+func (d *decoder) jumpTarget(def opcode, arg, next int) int {
+	if d.atLeast(3, 10) {
+		// Jump arguments count instructions rather than bytes
+		arg *= 2
+	}
+	switch def.arg {
+	case argJrel:
+		if strings.Contains(def.name, "BACKWARD") {
+			return next - arg
+		}
+		return next + arg
+	case argJabs:
+		return arg
+	}
+	return -1
+}
+
+func (d *decoder) argRepr(def opcode, arg, target int) string {
+	switch def.arg {
+	case argConst:
+		consts := d.code.GetConsts()
+		if arg < len(consts) {
+			return repr(consts[arg], d.major == 2)
+		}
+	case argName:
+		switch {
+		case def.name == "LOAD_GLOBAL" && d.atLeast(3, 11):
+			return d.withNull(d.name(arg>>1), arg&1 != 0, "NULL")
+		case def.name == "LOAD_ATTR" && d.atLeast(3, 12):
+			return d.withNull(d.name(arg>>1), arg&1 != 0, "NULL|self")
+		case def.name == "LOAD_SUPER_ATTR":
+			return d.withNull(d.name(arg>>2), arg&1 != 0, "NULL|self")
+		}
+		return d.name(arg)
+	case argJrel, argJabs:
+		return "to " + strconv.Itoa(target)
+	case argLocal:
+		switch def.name {
+		case "LOAD_FAST_LOAD_FAST", "STORE_FAST_LOAD_FAST", "STORE_FAST_STORE_FAST":
+			return d.local(arg>>4) + ", " + d.local(arg&15)
+		}
+		return d.local(arg)
+	case argFree:
+		if d.atLeast(3, 11) {
+			return index(d.code.GetLocalsPlusNames(), arg)
+		}
+		return index(append(d.code.GetCellVars(), d.code.GetFreeVars()...), arg)
+	case argCompare:
+		switch {
+		case d.atLeast(3, 13):
+			if arg&16 != 0 {
+				return "bool(" + index(cmpOp, arg>>5) + ")"
+			}
+			return index(cmpOp, arg>>5)
+		case d.atLeast(3, 12):
+			return index(cmpOp, arg>>4)
+		}
+		return index(cmpOp, arg)
+	case argPlain:
+		switch def.name {
+		case "BINARY_OP":
+			return index(nbOps, arg)
+		case "FORMAT_VALUE":
+			conv := formatValueConverters[arg&3]
+			if arg&4 != 0 {
+				if conv != "" {
+					conv += ", "
+				}
+				conv += "with format"
+			}
+			return conv
+		case "MAKE_FUNCTION", "SET_FUNCTION_ATTRIBUTE":
+			if !d.atLeast(3, 6) {
+				// Before 3.6 the argument counts default values
+				return ""
+			}
+			var flags []string
+			for i, flag := range functionFlags {
+				if arg&(1<<i) != 0 {
+					flags = append(flags, flag)
+				}
+			}
+			return strings.Join(flags, ", ")
+		case "CONVERT_VALUE":
+			return index(formatValueConverters, arg)
+		case "CALL_INTRINSIC_1":
+			return index(intrinsic1, arg)
+		case "CALL_INTRINSIC_2":
+			return index(intrinsic2, arg)
+		}
+	}
+	return ""
+}
+
+func (d *decoder) withNull(name string, null bool, what string) string {
+	if !null || name == "" {
+		return name
+	}
+	if d.atLeast(3, 13) {
+		return name + " + " + what
+	}
+	return what + " + " + name
+}
+
+func (d *decoder) name(i int) string {
+	return index(d.code.GetNames(), i)
+}
+
+func (d *decoder) local(i int) string {
+	if d.atLeast(3, 11) {
+		return index(d.code.GetLocalsPlusNames(), i)
+	}
+	return index(d.code.GetVarNames(), i)
+}
+
+func index(items []string, i int) string {
+	if i < 0 || i >= len(items) {
+		return ""
+	}
+	return items[i]
+}
+
+// ExceptionTable decodes co_exceptiontable of a Python 3.11+ code object
+func ExceptionTable(code *marshal.PyCodeObject) ([]ExceptionEntry, error) {
+	d, err := newDecoder(code)
+	if err != nil {
+		return nil, err
+	}
+	return d.exceptionTable(), nil
+}
+
+func (d *decoder) exceptionTable() []ExceptionEntry {
+	if !d.atLeast(3, 11) {
+		return nil
+	}
+	table := d.code.GetExceptionTable()
+	pos := 0
+	// Big endian varints of 6 bit chunks, bit 6 marks continuation
+	varint := func() (int, bool) {
+		if pos >= len(table) {
+			return 0, false
+		}
+		b := table[pos]
+		pos++
+		val := int(b & 63)
+		for b&64 != 0 {
+			if pos >= len(table) {
+				return 0, false
+			}
+			b = table[pos]
+			pos++
+			val = val<<6 | int(b&63)
+		}
+		return val, true
+	}
+
+	var entries []ExceptionEntry
+	for {
+		start, ok1 := varint()
+		length, ok2 := varint()
+		target, ok3 := varint()
+		depthLasti, ok4 := varint()
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			return entries
+		}
+		entries = append(entries, ExceptionEntry{
+			Start:  start * 2,
+			End:    (start + length) * 2,
+			Target: target * 2,
+			Depth:  depthLasti >> 1,
+			Lasti:  depthLasti&1 != 0,
+		})
+	}
+}
+
+// Disassemble writes a listing of code followed by the code objects nested in
+// its constants, in the format of Python's dis module.
+func Disassemble(w io.Writer, code *marshal.PyCodeObject) error {
+	var buf bytes.Buffer
+	if err := disassemble(&buf, code, make(map[*marshal.PyCodeObject]bool), 0); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// disassemble lists code and its nested code objects. Code objects shared
+// through marshal references, possibly recursively, are listed once.
+func disassemble(buf *bytes.Buffer, code *marshal.PyCodeObject, listed map[*marshal.PyCodeObject]bool, depth int) error {
+	if depth > maxCodeDepth {
+		return fmt.Errorf("code objects nested more than %d deep", maxCodeDepth)
+	}
+	listed[code] = true
+	d, err := newDecoder(code)
+	if err != nil {
+		return err
+	}
+	instructions := d.instructions(true)
+
+	linenoWidth := 0
+	for _, inst := range instructions {
+		if inst.NewLine {
+			linenoWidth = max(linenoWidth, 3, len(strconv.Itoa(inst.Line)))
+		}
+	}
+	offsetWidth := max(4, len(strconv.Itoa(len(code.GetBytecode())-2)))
+
+	for _, inst := range instructions {
+		if inst.NewLine && inst.Offset > 0 {
+			buf.WriteByte('\n')
+		}
+		var fields []string
+		if linenoWidth != 0 {
+			if inst.NewLine {
+				fields = append(fields, fmt.Sprintf("%*d", linenoWidth, inst.Line))
+			} else {
+				fields = append(fields, strings.Repeat(" ", linenoWidth))
+			}
+		}
+		fields = append(fields, "   ")
+		if inst.IsLabel {
+			fields = append(fields, ">>")
+		} else {
+			fields = append(fields, "  ")
+		}
+		fields = append(fields, fmt.Sprintf("%*d", offsetWidth, inst.Offset))
+		fields = append(fields, fmt.Sprintf("%-*s", opnameWidth, inst.Name))
+		if inst.HasArg {
+			fields = append(fields, fmt.Sprintf("%*d", opargWidth, inst.Arg))
+			if inst.ArgRepr != "" {
+				fields = append(fields, "("+inst.ArgRepr+")")
+			}
+		}
+		buf.WriteString(strings.TrimRight(strings.Join(fields, " "), " "))
+		buf.WriteByte('\n')
+	}
+
+	if entries := d.exceptionTable(); len(entries) != 0 {
+		buf.WriteString("ExceptionTable:\n")
+		for _, entry := range entries {
+			lasti := ""
+			if entry.Lasti {
+				lasti = " lasti"
+			}
+			fmt.Fprintf(buf, "  %d to %d -> %d [%d]%s\n", entry.Start, entry.End-2, entry.Target, entry.Depth, lasti)
+		}
+	}
+
+	for _, obj := range code.GetConsts() {
+		if nested, ok := marshal.AsCode(obj); ok && !listed[nested] {
+			fmt.Fprintf(buf, "\nDisassembly of %s:\n", repr(nested, false))
+			if err := disassemble(buf, nested, listed, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
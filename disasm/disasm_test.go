@@ -0,0 +1,124 @@
+package disasm
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"pyinstxtractor-go/marshal"
+)
+
+// loadCode decodes a Python 3.11 pyc of testdata
+func loadCode(t *testing.T, name string) *marshal.PyCodeObject {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := marshal.NewUnmarshalerForVersion(bytes.NewReader(data[16:]), 3, 11).Unmarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, ok := marshal.AsCode(obj)
+	if !ok {
+		t.Fatalf("%s holds a %T", name, obj)
+	}
+	return code
+}
+
+func TestReprRecursive(t *testing.T) {
+	list := marshal.NewList(marshal.NewTuple())
+	tuple := marshal.NewTuple(list)
+	list.SetItems([]marshal.Object{list, tuple})
+
+	tests := []struct {
+		obj  marshal.Object
+		want string
+	}{
+		{list, "[[...], ([...],)]"},
+		{tuple, "([[...], (...)],)"},
+	}
+	for _, test := range tests {
+		if got := repr(test.obj, false); got != test.want {
+			t.Errorf("repr = %s, want %s", got, test.want)
+		}
+	}
+
+	// Shared references double the tree at each level
+	shared := marshal.NewList()
+	for i := 0; i < 64; i++ {
+		shared = marshal.NewList(shared, shared)
+	}
+	if got := repr(shared, false); !strings.Contains(got, "...") || len(got) > 10*maxReprItems {
+		t.Errorf("repr of shared lists is %d bytes long", len(got))
+	}
+}
+
+func TestDisassembleCyclic(t *testing.T) {
+	// The constants of cyclic.pyc include l = [l] and d = {'self': d}
+	code := loadCode(t, "cyclic.pyc")
+	code.SetConsts(append(code.GetConsts(), code))
+
+	var listing bytes.Buffer
+	if err := Disassemble(&listing, code); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(listing.String(), "Disassembly of <code object f") {
+		t.Errorf("nested code object missing from\n%s", listing.String())
+	}
+
+	insts, err := Instructions(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	consts := code.GetConsts()
+	wants := map[string]bool{"[[...]]": false, "{'self': {...}}": false}
+	for i := range consts {
+		got := repr(consts[i], false)
+		if _, ok := wants[got]; ok {
+			wants[got] = true
+		}
+	}
+	for want, found := range wants {
+		if !found {
+			t.Errorf("no constant formatted as %s", want)
+		}
+	}
+	if len(insts) == 0 {
+		t.Error("no instructions")
+	}
+}
+
+func TestDisassembleDepth(t *testing.T) {
+	outer := loadCode(t, "cyclic.pyc")
+	code := outer
+	for i := 0; i < maxCodeDepth+10; i++ {
+		nested := loadCode(t, "cyclic.pyc")
+		code.SetConsts([]marshal.Object{nested})
+		code = nested
+	}
+	if err := Disassemble(&bytes.Buffer{}, outer); err == nil {
+		t.Error("deeply nested code objects were disassembled")
+	}
+}
+
+// FuzzDisassemble lists arbitrary code objects of the Python 3 versions
+func FuzzDisassemble(f *testing.F) {
+	data, err := os.ReadFile("testdata/cyclic.pyc")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(byte(11), data[16:])
+
+	f.Fuzz(func(t *testing.T, minor byte, data []byte) {
+		obj, err := marshal.NewUnmarshalerForVersion(bytes.NewReader(data), 3, int(minor%14)).Unmarshal()
+		if err != nil {
+			return
+		}
+		if code, ok := marshal.AsCode(obj); ok {
+			Disassemble(io.Discard, code)
+		}
+	})
+}
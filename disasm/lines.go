@@ -0,0 +1,133 @@
+package disasm
+
+// lineStarts maps the offset of every instruction starting a new source line
+// to that line number
+func (d *decoder) lineStarts() map[int]int {
+	table := d.code.GetLineTable()
+	starts := make(map[int]int)
+	switch {
+	case d.atLeast(3, 11):
+		d.locationTableStarts(table, starts)
+	case d.atLeast(3, 10):
+		d.lineTableStarts(table, starts)
+	default:
+		d.lnotabStarts(table, starts)
+	}
+	return starts
+}
+
+// lnotabStarts decodes co_lnotab, pairs of unsigned byte and line increments.
+// Line increments are signed from Python 3.6.
+func (d *decoder) lnotabStarts(table []byte, starts map[int]int) {
+	line := d.code.GetFirstLineNo()
+	lastLine := -1
+	addr := 0
+	for i := 0; i+1 < len(table); i += 2 {
+		if table[i] != 0 {
+			if line != lastLine {
+				starts[addr] = line
+				lastLine = line
+			}
+			addr += int(table[i])
+		}
+		if d.atLeast(3, 6) {
+			line += int(int8(table[i+1]))
+		} else {
+			line += int(table[i+1])
+		}
+	}
+	if line != lastLine {
+		starts[addr] = line
+	}
+}
+
+// lineTableStarts decodes the Python 3.10 co_linetable, pairs of byte and
+// signed line deltas where a line delta of -128 means no line number
+func (d *decoder) lineTableStarts(table []byte, starts map[int]int) {
+	line := d.code.GetFirstLineNo()
+	lastLine := -1
+	end := 0
+	for i := 0; i+1 < len(table); i += 2 {
+		sdelta, ldelta := int(table[i]), int(int8(table[i+1]))
+		start := end
+		end += sdelta
+		hasLine := ldelta != -128
+		if hasLine {
+			line += ldelta
+		}
+		if start != end && hasLine && line != lastLine {
+			starts[start] = line
+			lastLine = line
+		}
+	}
+}
+
+// locationTableStarts decodes the Python 3.11+ co_linetable. Every entry
+// starts with a byte holding a code and the number of code units covered,
+// followed by line and column data depending on the code.
+func (d *decoder) locationTableStarts(table []byte, starts map[int]int) {
+	pos := 0
+	// Little endian varints of 6 bit chunks, bit 6 marks continuation
+	varint := func() int {
+		val, shift := 0, 0
+		for pos < len(table) {
+			b := table[pos]
+			pos++
+			val |= int(b&63) << shift
+			shift += 6
+			if b&64 == 0 {
+				break
+			}
+		}
+		return val
+	}
+	svarint := func() int {
+		val := varint()
+		if val&1 != 0 {
+			return -(val >> 1)
+		}
+		return val >> 1
+	}
+
+	line := d.code.GetFirstLineNo()
+	lastLine := -1
+	addr := 0
+	for pos < len(table) {
+		first := table[pos]
+		pos++
+		code := int(first>>3) & 15
+		length := int(first&7) + 1
+
+		hasLine := true
+		switch {
+		case code == 15:
+			// No location
+			hasLine = false
+			if d.atLeast(3, 13) {
+				// From 3.13 a line following a gap is reported again
+				lastLine = -1
+			}
+		case code == 14:
+			// Long form
+			line += svarint()
+			varint() // end line delta
+			varint() // column
+			varint() // end column
+		case code == 13:
+			// No column info
+			line += svarint()
+		case code >= 10:
+			// One line form, with the columns in the next two bytes
+			line += code - 10
+			pos += 2
+		default:
+			// Short form, with the columns in the next byte
+			pos++
+		}
+		if hasLine && line != lastLine {
+			starts[addr] = line
+			lastLine = line
+		}
+		addr += 2 * length
+	}
+}
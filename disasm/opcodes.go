@@ -0,0 +1,663 @@
+package disasm
+
+// argKind says how the argument of an instruction is resolved
+type argKind uint8
+
+const (
+	argNone    argKind = iota // no argument
+	argPlain                  // plain integer
+	argConst                  // index into co_consts
+	argName                   // index into co_names
+	argLocal                  // index into co_varnames, co_localsplusnames from 3.11
+	argFree                   // index into co_cellvars + co_freevars, co_localsplusnames from 3.11
+	argCompare                // index into cmp_op
+	argJrel                   // jump relative to the next instruction
+	argJabs                   // jump to an absolute offset
+)
+
+type opcode struct {
+	name   string
+	arg    argKind
+	caches int // inline cache entries following the instruction (3.11+)
+}
+
+// opcodeTable maps opcodes to their definition. Unused opcodes have an empty
+// name.
+type opcodeTable [256]opcode
+
+// with returns a copy of t with the removed opcodes cleared and the added
+// ones set
+func (t opcodeTable) with(removed []int, added opcodeTable) opcodeTable {
+	for _, op := range removed {
+		t[op] = opcode{}
+	}
+	for op, def := range added {
+		if def.name != "" {
+			t[op] = def
+		}
+	}
+	return t
+}
+
+// Python 2.7
+var python27 = opcodeTable{
+	0:   {"STOP_CODE", argNone, 0},
+	1:   {"POP_TOP", argNone, 0},
+	2:   {"ROT_TWO", argNone, 0},
+	3:   {"ROT_THREE", argNone, 0},
+	4:   {"DUP_TOP", argNone, 0},
+	5:   {"ROT_FOUR", argNone, 0},
+	9:   {"NOP", argNone, 0},
+	10:  {"UNARY_POSITIVE", argNone, 0},
+	11:  {"UNARY_NEGATIVE", argNone, 0},
+	12:  {"UNARY_NOT", argNone, 0},
+	13:  {"UNARY_CONVERT", argNone, 0},
+	15:  {"UNARY_INVERT", argNone, 0},
+	19:  {"BINARY_POWER", argNone, 0},
+	20:  {"BINARY_MULTIPLY", argNone, 0},
+	21:  {"BINARY_DIVIDE", argNone, 0},
+	22:  {"BINARY_MODULO", argNone, 0},
+	23:  {"BINARY_ADD", argNone, 0},
+	24:  {"BINARY_SUBTRACT", argNone, 0},
+	25:  {"BINARY_SUBSCR", argNone, 0},
+	26:  {"BINARY_FLOOR_DIVIDE", argNone, 0},
+	27:  {"BINARY_TRUE_DIVIDE", argNone, 0},
+	28:  {"INPLACE_FLOOR_DIVIDE", argNone, 0},
+	29:  {"INPLACE_TRUE_DIVIDE", argNone, 0},
+	30:  {"SLICE+0", argNone, 0},
+	31:  {"SLICE+1", argNone, 0},
+	32:  {"SLICE+2", argNone, 0},
+	33:  {"SLICE+3", argNone, 0},
+	40:  {"STORE_SLICE+0", argNone, 0},
+	41:  {"STORE_SLICE+1", argNone, 0},
+	42:  {"STORE_SLICE+2", argNone, 0},
+	43:  {"STORE_SLICE+3", argNone, 0},
+	50:  {"DELETE_SLICE+0", argNone, 0},
+	51:  {"DELETE_SLICE+1", argNone, 0},
+	52:  {"DELETE_SLICE+2", argNone, 0},
+	53:  {"DELETE_SLICE+3", argNone, 0},
+	54:  {"STORE_MAP", argNone, 0},
+	55:  {"INPLACE_ADD", argNone, 0},
+	56:  {"INPLACE_SUBTRACT", argNone, 0},
+	57:  {"INPLACE_MULTIPLY", argNone, 0},
+	58:  {"INPLACE_DIVIDE", argNone, 0},
+	59:  {"INPLACE_MODULO", argNone, 0},
+	60:  {"STORE_SUBSCR", argNone, 0},
+	61:  {"DELETE_SUBSCR", argNone, 0},
+	62:  {"BINARY_LSHIFT", argNone, 0},
+	63:  {"BINARY_RSHIFT", argNone, 0},
+	64:  {"BINARY_AND", argNone, 0},
+	65:  {"BINARY_XOR", argNone, 0},
+	66:  {"BINARY_OR", argNone, 0},
+	67:  {"INPLACE_POWER", argNone, 0},
+	68:  {"GET_ITER", argNone, 0},
+	70:  {"PRINT_EXPR", argNone, 0},
+	71:  {"PRINT_ITEM", argNone, 0},
+	72:  {"PRINT_NEWLINE", argNone, 0},
+	73:  {"PRINT_ITEM_TO", argNone, 0},
+	74:  {"PRINT_NEWLINE_TO", argNone, 0},
+	75:  {"INPLACE_LSHIFT", argNone, 0},
+	76:  {"INPLACE_RSHIFT", argNone, 0},
+	77:  {"INPLACE_AND", argNone, 0},
+	78:  {"INPLACE_XOR", argNone, 0},
+	79:  {"INPLACE_OR", argNone, 0},
+	80:  {"BREAK_LOOP", argNone, 0},
+	81:  {"WITH_CLEANUP", argNone, 0},
+	82:  {"LOAD_LOCALS", argNone, 0},
+	83:  {"RETURN_VALUE", argNone, 0},
+	84:  {"IMPORT_STAR", argNone, 0},
+	85:  {"EXEC_STMT", argNone, 0},
+	86:  {"YIELD_VALUE", argNone, 0},
+	87:  {"POP_BLOCK", argNone, 0},
+	88:  {"END_FINALLY", argNone, 0},
+	89:  {"BUILD_CLASS", argNone, 0},
+	90:  {"STORE_NAME", argName, 0},
+	91:  {"DELETE_NAME", argName, 0},
+	92:  {"UNPACK_SEQUENCE", argPlain, 0},
+	93:  {"FOR_ITER", argJrel, 0},
+	94:  {"LIST_APPEND", argPlain, 0},
+	95:  {"STORE_ATTR", argName, 0},
+	96:  {"DELETE_ATTR", argName, 0},
+	97:  {"STORE_GLOBAL", argName, 0},
+	98:  {"DELETE_GLOBAL", argName, 0},
+	99:  {"DUP_TOPX", argPlain, 0},
+	100: {"LOAD_CONST", argConst, 0},
+	101: {"LOAD_NAME", argName, 0},
+	102: {"BUILD_TUPLE", argPlain, 0},
+	103: {"BUILD_LIST", argPlain, 0},
+	104: {"BUILD_SET", argPlain, 0},
+	105: {"BUILD_MAP", argPlain, 0},
+	106: {"LOAD_ATTR", argName, 0},
+	107: {"COMPARE_OP", argCompare, 0},
+	108: {"IMPORT_NAME", argName, 0},
+	109: {"IMPORT_FROM", argName, 0},
+	110: {"JUMP_FORWARD", argJrel, 0},
+	111: {"JUMP_IF_FALSE_OR_POP", argJabs, 0},
+	112: {"JUMP_IF_TRUE_OR_POP", argJabs, 0},
+	113: {"JUMP_ABSOLUTE", argJabs, 0},
+	114: {"POP_JUMP_IF_FALSE", argJabs, 0},
+	115: {"POP_JUMP_IF_TRUE", argJabs, 0},
+	116: {"LOAD_GLOBAL", argName, 0},
+	119: {"CONTINUE_LOOP", argJabs, 0},
+	120: {"SETUP_LOOP", argJrel, 0},
+	121: {"SETUP_EXCEPT", argJrel, 0},
+	122: {"SETUP_FINALLY", argJrel, 0},
+	124: {"LOAD_FAST", argLocal, 0},
+	125: {"STORE_FAST", argLocal, 0},
+	126: {"DELETE_FAST", argLocal, 0},
+	130: {"RAISE_VARARGS", argPlain, 0},
+	131: {"CALL_FUNCTION", argPlain, 0},
+	132: {"MAKE_FUNCTION", argPlain, 0},
+	133: {"BUILD_SLICE", argPlain, 0},
+	134: {"MAKE_CLOSURE", argPlain, 0},
+	135: {"LOAD_CLOSURE", argFree, 0},
+	136: {"LOAD_DEREF", argFree, 0},
+	137: {"STORE_DEREF", argFree, 0},
+	140: {"CALL_FUNCTION_VAR", argPlain, 0},
+	141: {"CALL_FUNCTION_KW", argPlain, 0},
+	142: {"CALL_FUNCTION_VAR_KW", argPlain, 0},
+	143: {"SETUP_WITH", argJrel, 0},
+	145: {"EXTENDED_ARG", argPlain, 0},
+	146: {"SET_ADD", argPlain, 0},
+	147: {"MAP_ADD", argPlain, 0},
+}
+
+// Python 3.0 dropped the print, exec and slice opcodes
+var python30 = python27.with(
+	[]int{13, 21, 30, 31, 32, 33, 40, 41, 42, 43, 50, 51, 52, 53, 58, 72, 73, 74, 82, 85, 114, 115, 145, 146, 147},
+	opcodeTable{
+		17:  {"SET_ADD", argNone, 0},
+		18:  {"LIST_APPEND", argNone, 0},
+		69:  {"STORE_LOCALS", argNone, 0},
+		71:  {"LOAD_BUILD_CLASS", argNone, 0},
+		89:  {"POP_EXCEPT", argNone, 0},
+		94:  {"UNPACK_EX", argPlain, 0},
+		111: {"JUMP_IF_FALSE", argJrel, 0},
+		112: {"JUMP_IF_TRUE", argJrel, 0},
+		143: {"EXTENDED_ARG", argPlain, 0},
+	},
+)
+
+// Python 3.1 added the conditional jumps which pop their argument
+var python31 = python30.with(
+	[]int{17, 18},
+	opcodeTable{
+		111: {"JUMP_IF_FALSE_OR_POP", argJabs, 0},
+		112: {"JUMP_IF_TRUE_OR_POP", argJabs, 0},
+		114: {"POP_JUMP_IF_FALSE", argJabs, 0},
+		115: {"POP_JUMP_IF_TRUE", argJabs, 0},
+		145: {"LIST_APPEND", argPlain, 0},
+		146: {"SET_ADD", argPlain, 0},
+		147: {"MAP_ADD", argPlain, 0},
+	},
+)
+
+// Python 3.2
+var python32 = python31.with(
+	[]int{99},
+	opcodeTable{
+		5:   {"DUP_TOP_TWO", argNone, 0},
+		138: {"DELETE_DEREF", argFree, 0},
+		143: {"SETUP_WITH", argJrel, 0},
+		144: {"EXTENDED_ARG", argPlain, 0},
+	},
+)
+
+// Python 3.3
+var python33 = python32.with(
+	[]int{0},
+	opcodeTable{
+		72: {"YIELD_FROM", argNone, 0},
+	},
+)
+
+// Python 3.4
+var python34 = python33.with(
+	[]int{69},
+	opcodeTable{
+		148: {"LOAD_CLASSDEREF", argFree, 0},
+	},
+)
+
+// Python 3.5 added the async opcodes
+var python35 = python34.with(
+	[]int{54},
+	opcodeTable{
+		16:  {"BINARY_MATRIX_MULTIPLY", argNone, 0},
+		17:  {"INPLACE_MATRIX_MULTIPLY", argNone, 0},
+		50:  {"GET_AITER", argNone, 0},
+		51:  {"GET_ANEXT", argNone, 0},
+		52:  {"BEFORE_ASYNC_WITH", argNone, 0},
+		69:  {"GET_YIELD_FROM_ITER", argNone, 0},
+		73:  {"GET_AWAITABLE", argNone, 0},
+		81:  {"WITH_CLEANUP_START", argNone, 0},
+		82:  {"WITH_CLEANUP_FINISH", argNone, 0},
+		149: {"BUILD_LIST_UNPACK", argPlain, 0},
+		150: {"BUILD_MAP_UNPACK", argPlain, 0},
+		151: {"BUILD_MAP_UNPACK_WITH_CALL", argPlain, 0},
+		152: {"BUILD_TUPLE_UNPACK", argPlain, 0},
+		153: {"BUILD_SET_UNPACK", argPlain, 0},
+		154: {"SETUP_ASYNC_WITH", argJrel, 0},
+	},
+)
+
+// Python 3.6 switched to 2 byte wordcode
+var python36 = python35.with(
+	[]int{134, 140},
+	opcodeTable{
+		85:  {"SETUP_ANNOTATIONS", argNone, 0},
+		127: {"STORE_ANNOTATION", argName, 0},
+		142: {"CALL_FUNCTION_EX", argPlain, 0},
+		155: {"FORMAT_VALUE", argPlain, 0},
+		156: {"BUILD_CONST_KEY_MAP", argPlain, 0},
+		157: {"BUILD_STRING", argPlain, 0},
+		158: {"BUILD_TUPLE_UNPACK_WITH_CALL", argPlain, 0},
+	},
+)
+
+// Python 3.7
+var python37 = python36.with(
+	[]int{127},
+	opcodeTable{
+		160: {"LOAD_METHOD", argName, 0},
+		161: {"CALL_METHOD", argPlain, 0},
+	},
+)
+
+// Python 3.8 removed the loop blocks
+var python38 = python37.with(
+	[]int{80, 119, 120, 121},
+	opcodeTable{
+		6:   {"ROT_FOUR", argNone, 0},
+		53:  {"BEGIN_FINALLY", argNone, 0},
+		54:  {"END_ASYNC_FOR", argNone, 0},
+		162: {"CALL_FINALLY", argJrel, 0},
+		163: {"POP_FINALLY", argPlain, 0},
+	},
+)
+
+// Python 3.9
+var python39 = python38.with(
+	[]int{53, 81, 88, 149, 150, 151, 152, 153, 158},
+	opcodeTable{
+		48:  {"RERAISE", argNone, 0},
+		49:  {"WITH_EXCEPT_START", argNone, 0},
+		74:  {"LOAD_ASSERTION_ERROR", argNone, 0},
+		82:  {"LIST_TO_TUPLE", argNone, 0},
+		117: {"IS_OP", argPlain, 0},
+		118: {"CONTAINS_OP", argPlain, 0},
+		121: {"JUMP_IF_NOT_EXC_MATCH", argJabs, 0},
+		162: {"LIST_EXTEND", argPlain, 0},
+		163: {"SET_UPDATE", argPlain, 0},
+		164: {"DICT_MERGE", argPlain, 0},
+		165: {"DICT_UPDATE", argPlain, 0},
+	},
+)
+
+// Python 3.10 counts jump arguments in instructions rather than bytes
+var python310 = python39.with(
+	[]int{48},
+	opcodeTable{
+		30:  {"GET_LEN", argNone, 0},
+		31:  {"MATCH_MAPPING", argNone, 0},
+		32:  {"MATCH_SEQUENCE", argNone, 0},
+		33:  {"MATCH_KEYS", argNone, 0},
+		34:  {"COPY_DICT_WITHOUT_KEYS", argNone, 0},
+		99:  {"ROT_N", argPlain, 0},
+		119: {"RERAISE", argPlain, 0},
+		129: {"GEN_START", argPlain, 0},
+		152: {"MATCH_CLASS", argPlain, 0},
+	},
+)
+
+// Python 3.11 renumbered the opcodes and added inline caches
+var python311 = opcodeTable{
+	0:   {"CACHE", argNone, 0},
+	1:   {"POP_TOP", argNone, 0},
+	2:   {"PUSH_NULL", argNone, 0},
+	9:   {"NOP", argNone, 0},
+	10:  {"UNARY_POSITIVE", argNone, 0},
+	11:  {"UNARY_NEGATIVE", argNone, 0},
+	12:  {"UNARY_NOT", argNone, 0},
+	15:  {"UNARY_INVERT", argNone, 0},
+	25:  {"BINARY_SUBSCR", argNone, 4},
+	30:  {"GET_LEN", argNone, 0},
+	31:  {"MATCH_MAPPING", argNone, 0},
+	32:  {"MATCH_SEQUENCE", argNone, 0},
+	33:  {"MATCH_KEYS", argNone, 0},
+	35:  {"PUSH_EXC_INFO", argNone, 0},
+	36:  {"CHECK_EXC_MATCH", argNone, 0},
+	37:  {"CHECK_EG_MATCH", argNone, 0},
+	49:  {"WITH_EXCEPT_START", argNone, 0},
+	50:  {"GET_AITER", argNone, 0},
+	51:  {"GET_ANEXT", argNone, 0},
+	52:  {"BEFORE_ASYNC_WITH", argNone, 0},
+	53:  {"BEFORE_WITH", argNone, 0},
+	54:  {"END_ASYNC_FOR", argNone, 0},
+	60:  {"STORE_SUBSCR", argNone, 1},
+	61:  {"DELETE_SUBSCR", argNone, 0},
+	68:  {"GET_ITER", argNone, 0},
+	69:  {"GET_YIELD_FROM_ITER", argNone, 0},
+	70:  {"PRINT_EXPR", argNone, 0},
+	71:  {"LOAD_BUILD_CLASS", argNone, 0},
+	74:  {"LOAD_ASSERTION_ERROR", argNone, 0},
+	75:  {"RETURN_GENERATOR", argNone, 0},
+	82:  {"LIST_TO_TUPLE", argNone, 0},
+	83:  {"RETURN_VALUE", argNone, 0},
+	84:  {"IMPORT_STAR", argNone, 0},
+	85:  {"SETUP_ANNOTATIONS", argNone, 0},
+	86:  {"YIELD_VALUE", argNone, 0},
+	87:  {"ASYNC_GEN_WRAP", argNone, 0},
+	88:  {"PREP_RERAISE_STAR", argNone, 0},
+	89:  {"POP_EXCEPT", argNone, 0},
+	90:  {"STORE_NAME", argName, 0},
+	91:  {"DELETE_NAME", argName, 0},
+	92:  {"UNPACK_SEQUENCE", argPlain, 1},
+	93:  {"FOR_ITER", argJrel, 0},
+	94:  {"UNPACK_EX", argPlain, 0},
+	95:  {"STORE_ATTR", argName, 4},
+	96:  {"DELETE_ATTR", argName, 0},
+	97:  {"STORE_GLOBAL", argName, 0},
+	98:  {"DELETE_GLOBAL", argName, 0},
+	99:  {"SWAP", argPlain, 0},
+	100: {"LOAD_CONST", argConst, 0},
+	101: {"LOAD_NAME", argName, 0},
+	102: {"BUILD_TUPLE", argPlain, 0},
+	103: {"BUILD_LIST", argPlain, 0},
+	104: {"BUILD_SET", argPlain, 0},
+	105: {"BUILD_MAP", argPlain, 0},
+	106: {"LOAD_ATTR", argName, 4},
+	107: {"COMPARE_OP", argCompare, 2},
+	108: {"IMPORT_NAME", argName, 0},
+	109: {"IMPORT_FROM", argName, 0},
+	110: {"JUMP_FORWARD", argJrel, 0},
+	111: {"JUMP_IF_FALSE_OR_POP", argJrel, 0},
+	112: {"JUMP_IF_TRUE_OR_POP", argJrel, 0},
+	114: {"POP_JUMP_FORWARD_IF_FALSE", argJrel, 0},
+	115: {"POP_JUMP_FORWARD_IF_TRUE", argJrel, 0},
+	116: {"LOAD_GLOBAL", argName, 5},
+	117: {"IS_OP", argPlain, 0},
+	118: {"CONTAINS_OP", argPlain, 0},
+	119: {"RERAISE", argPlain, 0},
+	120: {"COPY", argPlain, 0},
+	122: {"BINARY_OP", argPlain, 1},
+	123: {"SEND", argJrel, 0},
+	124: {"LOAD_FAST", argLocal, 0},
+	125: {"STORE_FAST", argLocal, 0},
+	126: {"DELETE_FAST", argLocal, 0},
+	128: {"POP_JUMP_FORWARD_IF_NOT_NONE", argJrel, 0},
+	129: {"POP_JUMP_FORWARD_IF_NONE", argJrel, 0},
+	130: {"RAISE_VARARGS", argPlain, 0},
+	131: {"GET_AWAITABLE", argPlain, 0},
+	132: {"MAKE_FUNCTION", argPlain, 0},
+	133: {"BUILD_SLICE", argPlain, 0},
+	134: {"JUMP_BACKWARD_NO_INTERRUPT", argJrel, 0},
+	135: {"MAKE_CELL", argFree, 0},
+	136: {"LOAD_CLOSURE", argFree, 0},
+	137: {"LOAD_DEREF", argFree, 0},
+	138: {"STORE_DEREF", argFree, 0},
+	139: {"DELETE_DEREF", argFree, 0},
+	140: {"JUMP_BACKWARD", argJrel, 0},
+	142: {"CALL_FUNCTION_EX", argPlain, 0},
+	144: {"EXTENDED_ARG", argPlain, 0},
+	145: {"LIST_APPEND", argPlain, 0},
+	146: {"SET_ADD", argPlain, 0},
+	147: {"MAP_ADD", argPlain, 0},
+	148: {"LOAD_CLASSDEREF", argFree, 0},
+	149: {"COPY_FREE_VARS", argPlain, 0},
+	151: {"RESUME", argPlain, 0},
+	152: {"MATCH_CLASS", argPlain, 0},
+	155: {"FORMAT_VALUE", argPlain, 0},
+	156: {"BUILD_CONST_KEY_MAP", argPlain, 0},
+	157: {"BUILD_STRING", argPlain, 0},
+	160: {"LOAD_METHOD", argName, 10},
+	162: {"LIST_EXTEND", argPlain, 0},
+	163: {"SET_UPDATE", argPlain, 0},
+	164: {"DICT_MERGE", argPlain, 0},
+	165: {"DICT_UPDATE", argPlain, 0},
+	166: {"PRECALL", argPlain, 1},
+	171: {"CALL", argPlain, 4},
+	172: {"KW_NAMES", argConst, 0},
+	173: {"POP_JUMP_BACKWARD_IF_NOT_NONE", argJrel, 0},
+	174: {"POP_JUMP_BACKWARD_IF_NONE", argJrel, 0},
+	175: {"POP_JUMP_BACKWARD_IF_FALSE", argJrel, 0},
+	176: {"POP_JUMP_BACKWARD_IF_TRUE", argJrel, 0},
+}
+
+// Python 3.12
+var python312 = opcodeTable{
+	0:   {"CACHE", argNone, 0},
+	1:   {"POP_TOP", argNone, 0},
+	2:   {"PUSH_NULL", argNone, 0},
+	3:   {"INTERPRETER_EXIT", argNone, 0},
+	4:   {"END_FOR", argNone, 0},
+	5:   {"END_SEND", argNone, 0},
+	9:   {"NOP", argNone, 0},
+	11:  {"UNARY_NEGATIVE", argNone, 0},
+	12:  {"UNARY_NOT", argNone, 0},
+	15:  {"UNARY_INVERT", argNone, 0},
+	17:  {"RESERVED", argNone, 0},
+	25:  {"BINARY_SUBSCR", argNone, 1},
+	26:  {"BINARY_SLICE", argNone, 0},
+	27:  {"STORE_SLICE", argNone, 0},
+	30:  {"GET_LEN", argNone, 0},
+	31:  {"MATCH_MAPPING", argNone, 0},
+	32:  {"MATCH_SEQUENCE", argNone, 0},
+	33:  {"MATCH_KEYS", argNone, 0},
+	35:  {"PUSH_EXC_INFO", argNone, 0},
+	36:  {"CHECK_EXC_MATCH", argNone, 0},
+	37:  {"CHECK_EG_MATCH", argNone, 0},
+	49:  {"WITH_EXCEPT_START", argNone, 0},
+	50:  {"GET_AITER", argNone, 0},
+	51:  {"GET_ANEXT", argNone, 0},
+	52:  {"BEFORE_ASYNC_WITH", argNone, 0},
+	53:  {"BEFORE_WITH", argNone, 0},
+	54:  {"END_ASYNC_FOR", argNone, 0},
+	55:  {"CLEANUP_THROW", argNone, 0},
+	60:  {"STORE_SUBSCR", argNone, 1},
+	61:  {"DELETE_SUBSCR", argNone, 0},
+	68:  {"GET_ITER", argNone, 0},
+	69:  {"GET_YIELD_FROM_ITER", argNone, 0},
+	71:  {"LOAD_BUILD_CLASS", argNone, 0},
+	74:  {"LOAD_ASSERTION_ERROR", argNone, 0},
+	75:  {"RETURN_GENERATOR", argNone, 0},
+	83:  {"RETURN_VALUE", argNone, 0},
+	85:  {"SETUP_ANNOTATIONS", argNone, 0},
+	87:  {"LOAD_LOCALS", argNone, 0},
+	89:  {"POP_EXCEPT", argNone, 0},
+	90:  {"STORE_NAME", argName, 0},
+	91:  {"DELETE_NAME", argName, 0},
+	92:  {"UNPACK_SEQUENCE", argPlain, 1},
+	93:  {"FOR_ITER", argJrel, 1},
+	94:  {"UNPACK_EX", argPlain, 0},
+	95:  {"STORE_ATTR", argName, 4},
+	96:  {"DELETE_ATTR", argName, 0},
+	97:  {"STORE_GLOBAL", argName, 0},
+	98:  {"DELETE_GLOBAL", argName, 0},
+	99:  {"SWAP", argPlain, 0},
+	100: {"LOAD_CONST", argConst, 0},
+	101: {"LOAD_NAME", argName, 0},
+	102: {"BUILD_TUPLE", argPlain, 0},
+	103: {"BUILD_LIST", argPlain, 0},
+	104: {"BUILD_SET", argPlain, 0},
+	105: {"BUILD_MAP", argPlain, 0},
+	106: {"LOAD_ATTR", argName, 9},
+	107: {"COMPARE_OP", argCompare, 1},
+	108: {"IMPORT_NAME", argName, 0},
+	109: {"IMPORT_FROM", argName, 0},
+	110: {"JUMP_FORWARD", argJrel, 0},
+	114: {"POP_JUMP_IF_FALSE", argJrel, 0},
+	115: {"POP_JUMP_IF_TRUE", argJrel, 0},
+	116: {"LOAD_GLOBAL", argName, 4},
+	117: {"IS_OP", argPlain, 0},
+	118: {"CONTAINS_OP", argPlain, 0},
+	119: {"RERAISE", argPlain, 0},
+	120: {"COPY", argPlain, 0},
+	121: {"RETURN_CONST", argConst, 0},
+	122: {"BINARY_OP", argPlain, 1},
+	123: {"SEND", argJrel, 1},
+	124: {"LOAD_FAST", argLocal, 0},
+	125: {"STORE_FAST", argLocal, 0},
+	126: {"DELETE_FAST", argLocal, 0},
+	127: {"LOAD_FAST_CHECK", argLocal, 0},
+	128: {"POP_JUMP_IF_NOT_NONE", argJrel, 0},
+	129: {"POP_JUMP_IF_NONE", argJrel, 0},
+	130: {"RAISE_VARARGS", argPlain, 0},
+	131: {"GET_AWAITABLE", argPlain, 0},
+	132: {"MAKE_FUNCTION", argPlain, 0},
+	133: {"BUILD_SLICE", argPlain, 0},
+	134: {"JUMP_BACKWARD_NO_INTERRUPT", argJrel, 0},
+	135: {"MAKE_CELL", argFree, 0},
+	136: {"LOAD_CLOSURE", argFree, 0},
+	137: {"LOAD_DEREF", argFree, 0},
+	138: {"STORE_DEREF", argFree, 0},
+	139: {"DELETE_DEREF", argFree, 0},
+	140: {"JUMP_BACKWARD", argJrel, 0},
+	141: {"LOAD_SUPER_ATTR", argName, 1},
+	142: {"CALL_FUNCTION_EX", argPlain, 0},
+	143: {"LOAD_FAST_AND_CLEAR", argLocal, 0},
+	144: {"EXTENDED_ARG", argPlain, 0},
+	145: {"LIST_APPEND", argPlain, 0},
+	146: {"SET_ADD", argPlain, 0},
+	147: {"MAP_ADD", argPlain, 0},
+	149: {"COPY_FREE_VARS", argPlain, 0},
+	150: {"YIELD_VALUE", argPlain, 0},
+	151: {"RESUME", argPlain, 0},
+	152: {"MATCH_CLASS", argPlain, 0},
+	155: {"FORMAT_VALUE", argPlain, 0},
+	156: {"BUILD_CONST_KEY_MAP", argPlain, 0},
+	157: {"BUILD_STRING", argPlain, 0},
+	162: {"LIST_EXTEND", argPlain, 0},
+	163: {"SET_UPDATE", argPlain, 0},
+	164: {"DICT_MERGE", argPlain, 0},
+	165: {"DICT_UPDATE", argPlain, 0},
+	171: {"CALL", argPlain, 3},
+	172: {"KW_NAMES", argConst, 0},
+	173: {"CALL_INTRINSIC_1", argPlain, 0},
+	174: {"CALL_INTRINSIC_2", argPlain, 0},
+	175: {"LOAD_FROM_DICT_OR_GLOBALS", argName, 0},
+	176: {"LOAD_FROM_DICT_OR_DEREF", argFree, 0},
+}
+
+// Python 3.13 renumbered the opcodes again
+var python313 = opcodeTable{
+	0:   {"CACHE", argNone, 0},
+	1:   {"BEFORE_ASYNC_WITH", argNone, 0},
+	2:   {"BEFORE_WITH", argNone, 0},
+	4:   {"BINARY_SLICE", argNone, 0},
+	5:   {"BINARY_SUBSCR", argNone, 1},
+	6:   {"CHECK_EG_MATCH", argNone, 0},
+	7:   {"CHECK_EXC_MATCH", argNone, 0},
+	8:   {"CLEANUP_THROW", argNone, 0},
+	9:   {"DELETE_SUBSCR", argNone, 0},
+	10:  {"END_ASYNC_FOR", argNone, 0},
+	11:  {"END_FOR", argNone, 0},
+	12:  {"END_SEND", argNone, 0},
+	13:  {"EXIT_INIT_CHECK", argNone, 0},
+	14:  {"FORMAT_SIMPLE", argNone, 0},
+	15:  {"FORMAT_WITH_SPEC", argNone, 0},
+	16:  {"GET_AITER", argNone, 0},
+	17:  {"RESERVED", argNone, 0},
+	18:  {"GET_ANEXT", argNone, 0},
+	19:  {"GET_ITER", argNone, 0},
+	20:  {"GET_LEN", argNone, 0},
+	21:  {"GET_YIELD_FROM_ITER", argNone, 0},
+	22:  {"INTERPRETER_EXIT", argNone, 0},
+	23:  {"LOAD_ASSERTION_ERROR", argNone, 0},
+	24:  {"LOAD_BUILD_CLASS", argNone, 0},
+	25:  {"LOAD_LOCALS", argNone, 0},
+	26:  {"MAKE_FUNCTION", argNone, 0},
+	27:  {"MATCH_KEYS", argNone, 0},
+	28:  {"MATCH_MAPPING", argNone, 0},
+	29:  {"MATCH_SEQUENCE", argNone, 0},
+	30:  {"NOP", argNone, 0},
+	31:  {"POP_EXCEPT", argNone, 0},
+	32:  {"POP_TOP", argNone, 0},
+	33:  {"PUSH_EXC_INFO", argNone, 0},
+	34:  {"PUSH_NULL", argNone, 0},
+	35:  {"RETURN_GENERATOR", argNone, 0},
+	36:  {"RETURN_VALUE", argNone, 0},
+	37:  {"SETUP_ANNOTATIONS", argNone, 0},
+	38:  {"STORE_SLICE", argNone, 0},
+	39:  {"STORE_SUBSCR", argNone, 1},
+	40:  {"TO_BOOL", argNone, 3},
+	41:  {"UNARY_INVERT", argNone, 0},
+	42:  {"UNARY_NEGATIVE", argNone, 0},
+	43:  {"UNARY_NOT", argNone, 0},
+	44:  {"WITH_EXCEPT_START", argNone, 0},
+	45:  {"BINARY_OP", argPlain, 1},
+	46:  {"BUILD_CONST_KEY_MAP", argPlain, 0},
+	47:  {"BUILD_LIST", argPlain, 0},
+	48:  {"BUILD_MAP", argPlain, 0},
+	49:  {"BUILD_SET", argPlain, 0},
+	50:  {"BUILD_SLICE", argPlain, 0},
+	51:  {"BUILD_STRING", argPlain, 0},
+	52:  {"BUILD_TUPLE", argPlain, 0},
+	53:  {"CALL", argPlain, 3},
+	54:  {"CALL_FUNCTION_EX", argPlain, 0},
+	55:  {"CALL_INTRINSIC_1", argPlain, 0},
+	56:  {"CALL_INTRINSIC_2", argPlain, 0},
+	57:  {"CALL_KW", argPlain, 0},
+	58:  {"COMPARE_OP", argCompare, 1},
+	59:  {"CONTAINS_OP", argPlain, 1},
+	60:  {"CONVERT_VALUE", argPlain, 0},
+	61:  {"COPY", argPlain, 0},
+	62:  {"COPY_FREE_VARS", argPlain, 0},
+	63:  {"DELETE_ATTR", argName, 0},
+	64:  {"DELETE_DEREF", argFree, 0},
+	65:  {"DELETE_FAST", argLocal, 0},
+	66:  {"DELETE_GLOBAL", argName, 0},
+	67:  {"DELETE_NAME", argName, 0},
+	68:  {"DICT_MERGE", argPlain, 0},
+	69:  {"DICT_UPDATE", argPlain, 0},
+	70:  {"ENTER_EXECUTOR", argPlain, 0},
+	71:  {"EXTENDED_ARG", argPlain, 0},
+	72:  {"FOR_ITER", argJrel, 1},
+	73:  {"GET_AWAITABLE", argPlain, 0},
+	74:  {"IMPORT_FROM", argName, 0},
+	75:  {"IMPORT_NAME", argName, 0},
+	76:  {"IS_OP", argPlain, 0},
+	77:  {"JUMP_BACKWARD", argJrel, 1},
+	78:  {"JUMP_BACKWARD_NO_INTERRUPT", argJrel, 0},
+	79:  {"JUMP_FORWARD", argJrel, 0},
+	80:  {"LIST_APPEND", argPlain, 0},
+	81:  {"LIST_EXTEND", argPlain, 0},
+	82:  {"LOAD_ATTR", argName, 9},
+	83:  {"LOAD_CONST", argConst, 0},
+	84:  {"LOAD_DEREF", argFree, 0},
+	85:  {"LOAD_FAST", argLocal, 0},
+	86:  {"LOAD_FAST_AND_CLEAR", argLocal, 0},
+	87:  {"LOAD_FAST_CHECK", argLocal, 0},
+	88:  {"LOAD_FAST_LOAD_FAST", argLocal, 0},
+	89:  {"LOAD_FROM_DICT_OR_DEREF", argFree, 0},
+	90:  {"LOAD_FROM_DICT_OR_GLOBALS", argName, 0},
+	91:  {"LOAD_GLOBAL", argName, 4},
+	92:  {"LOAD_NAME", argName, 0},
+	93:  {"LOAD_SUPER_ATTR", argName, 1},
+	94:  {"MAKE_CELL", argFree, 0},
+	95:  {"MAP_ADD", argPlain, 0},
+	96:  {"MATCH_CLASS", argPlain, 0},
+	97:  {"POP_JUMP_IF_FALSE", argJrel, 1},
+	98:  {"POP_JUMP_IF_NONE", argJrel, 1},
+	99:  {"POP_JUMP_IF_NOT_NONE", argJrel, 1},
+	100: {"POP_JUMP_IF_TRUE", argJrel, 1},
+	101: {"RAISE_VARARGS", argPlain, 0},
+	102: {"RERAISE", argPlain, 0},
+	103: {"RETURN_CONST", argConst, 0},
+	104: {"SEND", argJrel, 1},
+	105: {"SET_ADD", argPlain, 0},
+	106: {"SET_FUNCTION_ATTRIBUTE", argPlain, 0},
+	107: {"SET_UPDATE", argPlain, 0},
+	108: {"STORE_ATTR", argName, 4},
+	109: {"STORE_DEREF", argFree, 0},
+	110: {"STORE_FAST", argLocal, 0},
+	111: {"STORE_FAST_LOAD_FAST", argLocal, 0},
+	112: {"STORE_FAST_STORE_FAST", argLocal, 0},
+	113: {"STORE_GLOBAL", argName, 0},
+	114: {"STORE_NAME", argName, 0},
+	115: {"SWAP", argPlain, 0},
+	116: {"UNPACK_EX", argPlain, 0},
+	117: {"UNPACK_SEQUENCE", argPlain, 1},
+	118: {"YIELD_VALUE", argPlain, 0},
+	149: {"RESUME", argPlain, 0},
+}
@@ -0,0 +1,271 @@
+package disasm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"pyinstxtractor-go/marshal"
+)
+
+// maxReprItems bounds the objects formatted by a single repr. Constants
+// sharing references can form trees far larger than the pyc.
+const maxReprItems = 100000
+
+// repr formats a decoded constant the way Python's repr would. Code objects
+// are shown without their address.
+func repr(obj marshal.Object, python2 bool) string {
+	r := reprWriter{python2: python2, active: make(map[marshal.Object]bool)}
+	r.write(obj)
+	return r.sb.String()
+}
+
+type reprWriter struct {
+	sb      strings.Builder
+	python2 bool
+	// active holds the containers being formatted, which marshal references
+	// can make recursive
+	active map[marshal.Object]bool
+	items  int
+}
+
+// enter marks a container as being formatted, writing the placeholder
+// CPython uses for recursive containers if it already is
+func (r *reprWriter) enter(obj marshal.Object, placeholder string) bool {
+	if r.active[obj] {
+		r.sb.WriteString(placeholder)
+		return false
+	}
+	r.active[obj] = true
+	return true
+}
+
+func (r *reprWriter) write(obj marshal.Object) {
+	if r.items++; r.items > maxReprItems {
+		if r.items == maxReprItems+1 {
+			r.sb.WriteString("...")
+		}
+		return
+	}
+	sb := &r.sb
+	python2 := r.python2
+	switch v := obj.(type) {
+	case nil:
+		sb.WriteString("NULL")
+	case *marshal.PyNoneObject:
+		sb.WriteString("None")
+	case *marshal.PyBoolObject:
+		if v.GetValue() {
+			sb.WriteString("True")
+		} else {
+			sb.WriteString("False")
+		}
+	case *marshal.PyEllipsisObject:
+		sb.WriteString("Ellipsis")
+	case *marshal.PyStopIterObject:
+		sb.WriteString("StopIteration")
+	case *marshal.PyIntegerObject:
+		sb.WriteString(strconv.Itoa(v.GetValue()))
+	case *marshal.PyInt64Object:
+		sb.WriteString(strconv.FormatInt(v.GetValue(), 10))
+	case *marshal.PyLongObject:
+		sb.WriteString(v.BigInt().String())
+		if python2 {
+			sb.WriteByte('L')
+		}
+	case *marshal.PyFloatObject:
+		sb.WriteString(floatRepr(v.GetValue(), true))
+	case *marshal.PyComplexObject:
+		sb.WriteString(complexRepr(v.GetValue()))
+	case *marshal.PyStringObject:
+		switch {
+		case v.IsBytes() && python2:
+			quoteBytes(sb, v.GetBytes())
+		case v.IsBytes():
+			sb.WriteByte('b')
+			quoteBytes(sb, v.GetBytes())
+		case python2:
+			sb.WriteByte('u')
+			quoteString(sb, v.GetString(), true)
+		default:
+			quoteString(sb, v.GetString(), false)
+		}
+	case *marshal.PyListObject:
+		open, close := "[", "]"
+		if v.IsTuple() {
+			open, close = "(", ")"
+		}
+		if !r.enter(v, open+"..."+close) {
+			return
+		}
+		defer delete(r.active, v)
+		sb.WriteString(open)
+		r.writeItems(v.GetItems())
+		if v.IsTuple() && len(v.GetItems()) == 1 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(close)
+	case *marshal.PySetObject:
+		name := "set"
+		if v.IsFrozen() {
+			name = "frozenset"
+		}
+		if !r.enter(v, name+"(...)") {
+			return
+		}
+		defer delete(r.active, v)
+		items := v.GetItems()
+		switch {
+		case len(items) == 0:
+			sb.WriteString(name + "()")
+		case v.IsFrozen():
+			sb.WriteString("frozenset({")
+			r.writeItems(items)
+			sb.WriteString("})")
+		default:
+			sb.WriteByte('{')
+			r.writeItems(items)
+			sb.WriteByte('}')
+		}
+	case *marshal.PyDictObject:
+		if !r.enter(v, "{...}") {
+			return
+		}
+		defer delete(r.active, v)
+		sb.WriteByte('{')
+		for i, item := range v.GetItems() {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			r.write(item.Key)
+			sb.WriteString(": ")
+			r.write(item.Value)
+		}
+		sb.WriteByte('}')
+	case *marshal.PySliceObject:
+		if !r.enter(v, "slice(...)") {
+			return
+		}
+		defer delete(r.active, v)
+		sb.WriteString("slice(")
+		r.writeItems([]marshal.Object{v.GetStart(), v.GetStop(), v.GetStep()})
+		sb.WriteByte(')')
+	case *marshal.PyCodeObject:
+		fmt.Fprintf(sb, "<code object %s, file %q, line %d>", v.GetName(), v.GetFilename(), v.GetFirstLineNo())
+	default:
+		fmt.Fprintf(sb, "<%T>", obj)
+	}
+}
+
+func (r *reprWriter) writeItems(items []marshal.Object) {
+	for i, item := range items {
+		if i > 0 {
+			r.sb.WriteString(", ")
+		}
+		r.write(item)
+	}
+}
+
+// floatRepr formats f like Python's repr, switching to exponent notation
+// outside [1e-4, 1e16). Complex parts omit the trailing ".0".
+func floatRepr(f float64, pointZero bool) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	abs := math.Abs(f)
+	if abs != 0 && (abs < 1e-4 || abs >= 1e16) {
+		return strconv.FormatFloat(f, 'e', -1, 64)
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if pointZero && !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+func complexRepr(c complex128) string {
+	re, im := real(c), imag(c)
+	imText := floatRepr(im, false) + "j"
+	if re == 0 && !math.Signbit(re) {
+		return imText
+	}
+	if !strings.HasPrefix(imText, "-") {
+		imText = "+" + imText
+	}
+	return "(" + floatRepr(re, false) + imText + ")"
+}
+
+// quoteString quotes s like the str repr of Python 3, or the unicode repr of
+// Python 2, which escapes everything outside ASCII
+func quoteString(sb *strings.Builder, s string, python2 bool) {
+	quote := pickQuote(s)
+	sb.WriteByte(quote)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			// Lone surrogates and other invalid sequences
+			fmt.Fprintf(sb, "\\x%02x", s[i])
+			i++
+			continue
+		}
+		i += size
+		switch {
+		case r == '\\' || r == rune(quote):
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r == '\t':
+			sb.WriteString("\\t")
+		case r == '\n':
+			sb.WriteString("\\n")
+		case r == '\r':
+			sb.WriteString("\\r")
+		case r < 0x7f && r >= 0x20 || r > 0x7f && !python2 && strconv.IsPrint(r):
+			sb.WriteRune(r)
+		case r < 0x100:
+			fmt.Fprintf(sb, "\\x%02x", r)
+		case r < 0x10000:
+			fmt.Fprintf(sb, "\\u%04x", r)
+		default:
+			fmt.Fprintf(sb, "\\U%08x", r)
+		}
+	}
+	sb.WriteByte(quote)
+}
+
+// quoteBytes quotes b like Python's bytes repr, without the b prefix
+func quoteBytes(sb *strings.Builder, b []byte) {
+	quote := pickQuote(string(b))
+	sb.WriteByte(quote)
+	for _, c := range b {
+		switch {
+		case c == '\\' || c == quote:
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c == '\t':
+			sb.WriteString("\\t")
+		case c == '\n':
+			sb.WriteString("\\n")
+		case c == '\r':
+			sb.WriteString("\\r")
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(sb, "\\x%02x", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte(quote)
+}
+
+func pickQuote(s string) byte {
+	if strings.Contains(s, "'") && !strings.Contains(s, "\"") {
+		return '"'
+	}
+	return '\''
+}
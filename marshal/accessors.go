@@ -0,0 +1,75 @@
+package marshal
+
+import (
+	"math/big"
+)
+
+// Checked conversions for walking decoded objects whose shape comes from
+// untrusted input. Each returns false when obj doesn't have the expected type.
+
+// AsSequence returns the items of a list or tuple
+func AsSequence(obj Object) ([]Object, bool) {
+	if seq, ok := obj.(*PyListObject); ok {
+		return seq.GetItems(), true
+	}
+	return nil, false
+}
+
+// AsString returns the contents of any string or bytes object
+func AsString(obj Object) (string, bool) {
+	if str, ok := obj.(*PyStringObject); ok {
+		return str.GetString(), true
+	}
+	return "", false
+}
+
+// AsInt returns the value of an int or bool object. Longs are accepted when
+// they fit in an int.
+func AsInt(obj Object) (int, bool) {
+	switch v := obj.(type) {
+	case *PyIntegerObject:
+		return v.GetValue(), true
+	case *PyInt64Object:
+		if int64(int(v.value)) != v.value {
+			return 0, false
+		}
+		return int(v.value), true
+	case *PyLongObject:
+		if !v.value.IsInt64() || int64(int(v.value.Int64())) != v.value.Int64() {
+			return 0, false
+		}
+		return int(v.value.Int64()), true
+	case *PyBoolObject:
+		if v.GetValue() {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// AsBigInt returns the value of any integer object
+func AsBigInt(obj Object) (*big.Int, bool) {
+	switch v := obj.(type) {
+	case *PyIntegerObject:
+		return big.NewInt(int64(v.GetValue())), true
+	case *PyInt64Object:
+		return big.NewInt(v.value), true
+	case *PyLongObject:
+		return v.BigInt(), true
+	}
+	return nil, false
+}
+
+// AsDict returns the items of a dict in their marshalled order
+func AsDict(obj Object) ([]PyDictItem, bool) {
+	if dict, ok := obj.(*PyDictObject); ok {
+		return dict.GetItems(), true
+	}
+	return nil, false
+}
+
+func AsCode(obj Object) (*PyCodeObject, bool) {
+	code, ok := obj.(*PyCodeObject)
+	return code, ok
+}
@@ -0,0 +1,154 @@
+package marshal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+var ErrUnsupportedVersion = errors.New("object can't be marshalled in this version")
+
+// Marshaler writes objects in the CPython marshal format. Objects which were
+// decoded by an Unmarshaler keep their typecodes and references, so that
+// unmodified objects are written back byte for byte.
+type Marshaler struct {
+	writer  io.Writer
+	version int
+	refs    map[Object]int
+	// interned numbers the Python 2 interned strings written so far, for
+	// TYPE_STRINGREF
+	interned map[*PyStringObject]int
+	depth    int
+	err      error
+}
+
+func NewMarshaler(w io.Writer) *Marshaler {
+	return NewMarshalerForVersion(w, MARSHAL_VERSION)
+}
+
+// NewMarshalerForVersion returns a marshaler writing the given marshal
+// format version (0 to 4, or 5 for Python 3.14 slices). Older versions lack
+// references (< 3), binary floats (< 2) and the compact ASCII string and
+// small tuple types (< 4).
+func NewMarshalerForVersion(w io.Writer, version int) *Marshaler {
+	return &Marshaler{writer: w, version: version}
+}
+
+// Marshal writes obj. References are resolved within a single call, like
+// marshal.dumps.
+func (m *Marshaler) Marshal(obj Object) error {
+	if m.version < 0 || m.version > 5 {
+		return fmt.Errorf("%w: unknown marshal version %d", ErrUnsupportedVersion, m.version)
+	}
+	m.refs = make(map[Object]int)
+	m.interned = make(map[*PyStringObject]int)
+	m.depth = 0
+	m.err = nil
+	return m.writeObject(obj)
+}
+
+// Marshal returns the marshalled bytes of obj
+func Marshal(obj Object, version int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewMarshalerForVersion(&buf, version).Marshal(obj); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (m *Marshaler) writeObject(obj Object) error {
+	if m.err != nil {
+		return m.err
+	}
+	if obj == nil {
+		return m.fail(errors.New("marshal: can't marshal a nil object"))
+	}
+	if m.depth >= MAX_MARSHAL_STACK_DEPTH {
+		return m.fail(errors.New("marshal: object too deeply nested to marshal"))
+	}
+
+	if idx, ok := m.refs[obj]; ok {
+		m.writeByte(TYPE_REF)
+		m.writeInt32(int32(idx))
+		return m.err
+	}
+
+	m.depth++
+	err := obj.w_object(m)
+	m.depth--
+	return err
+}
+
+// writeType writes the typecode of obj, adding FLAG_REF when obj was decoded
+// with it
+func (m *Marshaler) writeType(obj Object, typecode byte) {
+	if m.version >= 3 && obj.ref().flagged {
+		m.refs[obj] = len(m.refs)
+		typecode |= FLAG_REF
+	}
+	m.writeByte(typecode)
+}
+
+func (m *Marshaler) fail(err error) error {
+	if m.err == nil {
+		m.err = err
+	}
+	return m.err
+}
+
+func (m *Marshaler) write(data []byte) {
+	if m.err != nil {
+		return
+	}
+	if _, err := m.writer.Write(data); err != nil {
+		m.err = err
+	}
+}
+
+func (m *Marshaler) writeByte(value byte) {
+	m.write([]byte{value})
+}
+
+func (m *Marshaler) writeInt32(value int32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(value))
+	m.write(buf[:])
+}
+
+func (m *Marshaler) writeSize(size int) {
+	if size > SIZE32_MAX {
+		m.fail(errors.New("marshal: object too large to marshal"))
+		return
+	}
+	m.writeInt32(int32(size))
+}
+
+func (m *Marshaler) writeFloat64(value float64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(value))
+	m.write(buf[:])
+}
+
+// writeFloatText writes a float as text, as marshal versions < 2 do. The
+// original text is reused when the float was decoded from text.
+func (m *Marshaler) writeFloatText(value float64, text string) {
+	if text == "" {
+		switch {
+		case math.IsInf(value, 1):
+			text = "inf"
+		case math.IsInf(value, -1):
+			text = "-inf"
+		case math.IsNaN(value):
+			text = "nan"
+		default:
+			// Same as repr() with 17 significant digits
+			text = strconv.FormatFloat(value, 'g', 17, 64)
+		}
+	}
+	m.writeByte(byte(len(text)))
+	m.write([]byte(text))
+}
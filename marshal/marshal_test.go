@@ -0,0 +1,85 @@
+package marshal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRoundTrip decodes and re-encodes testdata/sample.py as compiled by
+// each Python version, which must give back the same bytes
+func TestRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("testdata/sample.*.pyc")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no fixtures: %v", err)
+	}
+	for _, file := range files {
+		var major, minor int
+		if _, err := fmt.Sscanf(filepath.Base(file), "sample.%d.%d.pyc", &major, &minor); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		t.Run(fmt.Sprintf("%d.%d", major, minor), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, version := 16, 4
+			switch {
+			case major == 2:
+				header, version = 8, 2
+			case minor < 7:
+				header = 12
+			}
+			data = data[header:]
+
+			obj, err := NewUnmarshalerForVersion(bytes.NewReader(data), major, minor).Unmarshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := Marshal(obj, version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, data) {
+				n := 0
+				for n < len(out) && n < len(data) && out[n] == data[n] {
+					n++
+				}
+				t.Errorf("output differs at offset %d of %d", n, len(data))
+			}
+		})
+	}
+}
+
+func TestMarshalStringRef(t *testing.T) {
+	// ('ab', 'ab', 'cd', 'ab', 'cd') with the repeats as TYPE_STRINGREF
+	data := []byte("(\x05\x00\x00\x00" +
+		"t\x02\x00\x00\x00ab" + "R\x00\x00\x00\x00" + "t\x02\x00\x00\x00cd" +
+		"R\x00\x00\x00\x00" + "R\x01\x00\x00\x00")
+	obj, err := NewUnmarshalerForVersion(bytes.NewReader(data), 2, 7).Unmarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		version int
+		want    []byte
+	}{
+		{2, data},
+		{1, data},
+		// Version 0 has no interned strings
+		{0, []byte("(\x05\x00\x00\x00" +
+			"s\x02\x00\x00\x00ab" + "s\x02\x00\x00\x00ab" + "s\x02\x00\x00\x00cd" +
+			"s\x02\x00\x00\x00ab" + "s\x02\x00\x00\x00cd")},
+	}
+	for _, test := range tests {
+		out, err := Marshal(obj, test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, test.want) {
+			t.Errorf("version %d: got %q, want %q", test.version, out, test.want)
+		}
+	}
+}
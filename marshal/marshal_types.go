@@ -1,10 +1,7 @@
 package marshal
 
-import (
-)
-
 const (
-	MARSHAL_VERSION     = 3
+	MARSHAL_VERSION     = 4
 	TYPE_NULL           = '0'
 	TYPE_NONE           = 'N'
 	TYPE_FALSE          = 'F'
@@ -37,6 +34,12 @@ const (
 	TYPE_SHORT_ASCII          = 'z'
 	TYPE_SHORT_ASCII_INTERNED = 'Z'
 
+	TYPE_SLICE = ':' // Python 3.14+
+
+	// Python 2 only
+	TYPE_INT64     = 'I' // 64 bit int written by 64 bit builds
+	TYPE_STRINGREF = 'R' // index into the table of interned strings
+
 	// We assume that Python ints are stored internally in base some power of
 	// 2**15; for the sake of portability we'll always read and write them in base
 	// exactly 2**15.
@@ -44,6 +47,15 @@ const (
 	PyLong_MARSHAL_SHIFT = 15
 	PyLong_MARSHAL_BASE  = (1 << PyLong_MARSHAL_SHIFT)
 	PyLong_MARSHAL_MASK  = (PyLong_MARSHAL_BASE - 1)
+
+	// Kinds of entries in co_localspluskinds (Python 3.11+)
+	CO_FAST_LOCAL = 0x20
+	CO_FAST_CELL  = 0x40
+	CO_FAST_FREE  = 0x80
+
+	// Python version whose layout is assumed when none is given
+	DEFAULT_PYTHON_MAJOR = 3
+	DEFAULT_PYTHON_MINOR = 11
 )
 
 
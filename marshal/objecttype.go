@@ -1,5 +1,18 @@
 package marshal
 
-type _object interface {
-	r_object() _object
+// Object is a decoded Python object
+type Object interface {
+	r_object(su *SimpleUnmarshaler) (Object, error)
+	w_object(m *Marshaler) error
+	ref() *refInfo
+}
+
+// refInfo records whether an object was marshalled with FLAG_REF, so that
+// marshalling it again reproduces the original references
+type refInfo struct {
+	flagged bool
+}
+
+func (ri *refInfo) ref() *refInfo {
+	return ri
 }
@@ -0,0 +1,295 @@
+package marshal
+
+// PyCodeObject is a code object. The fields present depend on the Python
+// version which produced it, absent fields are left at their zero value.
+type PyCodeObject struct {
+	refInfo
+	pythonMajor     int
+	pythonMinor     int
+	argcount        int32
+	posonlyargcount int32 // 3.8+
+	kwonlyargcount  int32 // 3.0+
+	nlocals         int32 // < 3.11
+	stacksize       int32
+	flags           int32
+	code            Object
+	consts          Object
+	names           Object
+	varnames        Object // < 3.11
+	freevars        Object // < 3.11
+	cellvars        Object // < 3.11
+	localsplusnames Object // 3.11+
+	localspluskinds Object // 3.11+
+	filename        Object
+	name            Object
+	qualname        Object // 3.11+
+	firstlineno     int32
+	linetable       Object // co_lnotab before 3.10
+	exceptiontable  Object // 3.11+
+}
+
+func (pco *PyCodeObject) atLeast(major, minor int) bool {
+	return pco.pythonMajor > major || (pco.pythonMajor == major && pco.pythonMinor >= minor)
+}
+
+func (pco *PyCodeObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	// Fields are read in sequence, stopping at the first error
+	var err error
+	readInt := func(field string, dst *int32) {
+		if err == nil {
+			*dst, err = su.readInt32(field)
+		}
+	}
+	readObject := func(field string, dst *Object) {
+		if err == nil {
+			*dst, err = su.readChild("." + field)
+		}
+	}
+
+	readInt("co_argcount", &pco.argcount)
+	if pco.atLeast(3, 8) {
+		readInt("co_posonlyargcount", &pco.posonlyargcount)
+	}
+	if pco.atLeast(3, 0) {
+		readInt("co_kwonlyargcount", &pco.kwonlyargcount)
+	}
+	if !pco.atLeast(3, 11) {
+		readInt("co_nlocals", &pco.nlocals)
+	}
+	readInt("co_stacksize", &pco.stacksize)
+	readInt("co_flags", &pco.flags)
+	readObject("co_code", &pco.code)
+	readObject("co_consts", &pco.consts)
+	readObject("co_names", &pco.names)
+	if pco.atLeast(3, 11) {
+		readObject("co_localsplusnames", &pco.localsplusnames)
+		readObject("co_localspluskinds", &pco.localspluskinds)
+	} else {
+		readObject("co_varnames", &pco.varnames)
+		readObject("co_freevars", &pco.freevars)
+		readObject("co_cellvars", &pco.cellvars)
+	}
+	readObject("co_filename", &pco.filename)
+	readObject("co_name", &pco.name)
+	if pco.atLeast(3, 11) {
+		readObject("co_qualname", &pco.qualname)
+	}
+	readInt("co_firstlineno", &pco.firstlineno)
+	readObject("co_linetable", &pco.linetable)
+	if pco.atLeast(3, 11) {
+		readObject("co_exceptiontable", &pco.exceptiontable)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pco, nil
+}
+
+func (pco *PyCodeObject) w_object(m *Marshaler) error {
+	m.writeType(pco, TYPE_CODE)
+	writeInt := func(value int32) {
+		m.writeInt32(value)
+	}
+	writeObject := func(obj Object) {
+		if m.err == nil {
+			m.writeObject(obj)
+		}
+	}
+
+	writeInt(pco.argcount)
+	if pco.atLeast(3, 8) {
+		writeInt(pco.posonlyargcount)
+	}
+	if pco.atLeast(3, 0) {
+		writeInt(pco.kwonlyargcount)
+	}
+	if !pco.atLeast(3, 11) {
+		writeInt(pco.nlocals)
+	}
+	writeInt(pco.stacksize)
+	writeInt(pco.flags)
+	writeObject(pco.code)
+	writeObject(pco.consts)
+	writeObject(pco.names)
+	if pco.atLeast(3, 11) {
+		writeObject(pco.localsplusnames)
+		writeObject(pco.localspluskinds)
+	} else {
+		writeObject(pco.varnames)
+		writeObject(pco.freevars)
+		writeObject(pco.cellvars)
+	}
+	writeObject(pco.filename)
+	writeObject(pco.name)
+	if pco.atLeast(3, 11) {
+		writeObject(pco.qualname)
+	}
+	writeInt(pco.firstlineno)
+	writeObject(pco.linetable)
+	if pco.atLeast(3, 11) {
+		writeObject(pco.exceptiontable)
+	}
+	return m.err
+}
+
+// SetConsts replaces co_consts, keeping the original tuple object so its
+// typecode and reference are preserved
+func (pco *PyCodeObject) SetConsts(consts []Object) {
+	if tuple, ok := pco.consts.(*PyListObject); ok {
+		tuple.SetItems(consts)
+		return
+	}
+	pco.consts = NewTuple(consts...)
+}
+
+// GetPythonVersion returns the Python version whose layout was used to decode
+// the code object
+func (pco *PyCodeObject) GetPythonVersion() (int, int) {
+	return pco.pythonMajor, pco.pythonMinor
+}
+
+func (pco *PyCodeObject) GetArgCount() int {
+	return int(pco.argcount)
+}
+
+func (pco *PyCodeObject) GetPosOnlyArgCount() int {
+	return int(pco.posonlyargcount)
+}
+
+func (pco *PyCodeObject) GetKwOnlyArgCount() int {
+	return int(pco.kwonlyargcount)
+}
+
+func (pco *PyCodeObject) GetNLocals() int {
+	return int(pco.nlocals)
+}
+
+func (pco *PyCodeObject) GetStackSize() int {
+	return int(pco.stacksize)
+}
+
+func (pco *PyCodeObject) GetFlags() int {
+	return int(pco.flags)
+}
+
+func (pco *PyCodeObject) GetCode() Object {
+	return pco.code
+}
+
+// GetConsts returns the items of co_consts
+func (pco *PyCodeObject) GetConsts() []Object {
+	return tupleItems(pco.consts)
+}
+
+// GetNames returns co_names
+func (pco *PyCodeObject) GetNames() []string {
+	return tupleStrings(pco.names)
+}
+
+// GetVarNames returns co_varnames. From Python 3.11 these are derived from
+// co_localsplusnames.
+func (pco *PyCodeObject) GetVarNames() []string {
+	if pco.atLeast(3, 11) {
+		return pco.localsPlusNamesOfKind(CO_FAST_LOCAL)
+	}
+	return tupleStrings(pco.varnames)
+}
+
+// GetFreeVars returns co_freevars
+func (pco *PyCodeObject) GetFreeVars() []string {
+	if pco.atLeast(3, 11) {
+		return pco.localsPlusNamesOfKind(CO_FAST_FREE)
+	}
+	return tupleStrings(pco.freevars)
+}
+
+// GetCellVars returns co_cellvars
+func (pco *PyCodeObject) GetCellVars() []string {
+	if pco.atLeast(3, 11) {
+		return pco.localsPlusNamesOfKind(CO_FAST_CELL)
+	}
+	return tupleStrings(pco.cellvars)
+}
+
+func (pco *PyCodeObject) GetLocalsPlusNames() []string {
+	return tupleStrings(pco.localsplusnames)
+}
+
+func (pco *PyCodeObject) GetLocalsPlusKinds() []byte {
+	if kinds, ok := pco.localspluskinds.(*PyStringObject); ok {
+		return kinds.GetBytes()
+	}
+	return nil
+}
+
+func (pco *PyCodeObject) localsPlusNamesOfKind(kind byte) []string {
+	names := pco.GetLocalsPlusNames()
+	kinds := pco.GetLocalsPlusKinds()
+	var result []string
+	for i, name := range names {
+		if i < len(kinds) && kinds[i]&kind != 0 {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+func (pco *PyCodeObject) GetFilename() string {
+	return objectString(pco.filename)
+}
+
+func (pco *PyCodeObject) GetName() string {
+	return objectString(pco.name)
+}
+
+// GetQualname returns co_qualname, or co_name before Python 3.11
+func (pco *PyCodeObject) GetQualname() string {
+	if pco.qualname == nil {
+		return pco.GetName()
+	}
+	return objectString(pco.qualname)
+}
+
+func (pco *PyCodeObject) GetFirstLineNo() int {
+	return int(pco.firstlineno)
+}
+
+// GetLineTable returns co_linetable, or co_lnotab before Python 3.10
+func (pco *PyCodeObject) GetLineTable() []byte {
+	return objectBytes(pco.linetable)
+}
+
+func (pco *PyCodeObject) GetExceptionTable() []byte {
+	return objectBytes(pco.exceptiontable)
+}
+
+// GetBytecode returns co_code
+func (pco *PyCodeObject) GetBytecode() []byte {
+	return objectBytes(pco.code)
+}
+
+func tupleItems(obj Object) []Object {
+	items, _ := AsSequence(obj)
+	return items
+}
+
+func tupleStrings(obj Object) []string {
+	var result []string
+	for _, item := range tupleItems(obj) {
+		str, _ := AsString(item)
+		result = append(result, str)
+	}
+	return result
+}
+
+func objectString(obj Object) string {
+	str, _ := AsString(obj)
+	return str
+}
+
+func objectBytes(obj Object) []byte {
+	if str, ok := obj.(*PyStringObject); ok {
+		return str.GetBytes()
+	}
+	return nil
+}
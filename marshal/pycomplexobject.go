@@ -0,0 +1,51 @@
+package marshal
+
+type PyComplexObject struct {
+	refInfo
+	real     float64
+	imag     float64
+	realText string // original text of a TYPE_COMPLEX
+	imagText string
+	typecode byte
+}
+
+func (pco *PyComplexObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	var err error
+	if pco.typecode == TYPE_COMPLEX {
+		if pco.real, pco.realText, err = readFloatString(su); err != nil {
+			return nil, err
+		}
+		if pco.imag, pco.imagText, err = readFloatString(su); err != nil {
+			return nil, err
+		}
+		return pco, nil
+	}
+	if pco.real, err = su.readFloat64("binary complex"); err != nil {
+		return nil, err
+	}
+	if pco.imag, err = su.readFloat64("binary complex"); err != nil {
+		return nil, err
+	}
+	return pco, nil
+}
+
+func (pco *PyComplexObject) GetValue() complex128 {
+	return complex(pco.real, pco.imag)
+}
+
+func (pco *PyComplexObject) w_object(m *Marshaler) error {
+	if m.version < 2 || pco.typecode == TYPE_COMPLEX {
+		m.writeType(pco, TYPE_COMPLEX)
+		m.writeFloatText(pco.real, pco.realText)
+		m.writeFloatText(pco.imag, pco.imagText)
+	} else {
+		m.writeType(pco, TYPE_BINARY_COMPLEX)
+		m.writeFloat64(pco.real)
+		m.writeFloat64(pco.imag)
+	}
+	return m.err
+}
+
+func NewComplex(value complex128) *PyComplexObject {
+	return &PyComplexObject{real: real(value), imag: imag(value), typecode: TYPE_BINARY_COMPLEX}
+}
@@ -0,0 +1,78 @@
+package marshal
+
+// PyNoneObject represents None
+type PyNoneObject struct {
+	refInfo
+}
+
+func (pno *PyNoneObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	return pno, nil
+}
+
+// PyBoolObject represents True and False
+type PyBoolObject struct {
+	refInfo
+	value bool
+}
+
+func (pbo *PyBoolObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	return pbo, nil
+}
+
+func (pbo *PyBoolObject) GetValue() bool {
+	return pbo.value
+}
+
+// PyEllipsisObject represents Ellipsis (...)
+type PyEllipsisObject struct {
+	refInfo
+}
+
+func (peo *PyEllipsisObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	return peo, nil
+}
+
+// PyStopIterObject represents StopIteration
+type PyStopIterObject struct {
+	refInfo
+}
+
+func (psio *PyStopIterObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	return psio, nil
+}
+
+func (pno *PyNoneObject) w_object(m *Marshaler) error {
+	m.writeType(pno, TYPE_NONE)
+	return m.err
+}
+
+func (pbo *PyBoolObject) w_object(m *Marshaler) error {
+	if pbo.value {
+		m.writeType(pbo, TYPE_TRUE)
+	} else {
+		m.writeType(pbo, TYPE_FALSE)
+	}
+	return m.err
+}
+
+func (peo *PyEllipsisObject) w_object(m *Marshaler) error {
+	m.writeType(peo, TYPE_ELLIPSIS)
+	return m.err
+}
+
+func (psio *PyStopIterObject) w_object(m *Marshaler) error {
+	m.writeType(psio, TYPE_STOPITER)
+	return m.err
+}
+
+func NewNone() *PyNoneObject {
+	return &PyNoneObject{}
+}
+
+func NewBool(value bool) *PyBoolObject {
+	return &PyBoolObject{value: value}
+}
+
+func NewEllipsis() *PyEllipsisObject {
+	return &PyEllipsisObject{}
+}
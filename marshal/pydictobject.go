@@ -0,0 +1,63 @@
+package marshal
+
+import (
+	"fmt"
+)
+
+type PyDictItem struct {
+	Key   Object
+	Value Object
+}
+
+type PyDictObject struct {
+	refInfo
+	items []PyDictItem
+}
+
+func (pdo *PyDictObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	for i := 0; ; i++ {
+		su.path = append(su.path, fmt.Sprintf("{%d}", i))
+		key, err := su.readObject()
+		su.path = su.path[:len(su.path)-1]
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			// TYPE_NULL terminates the dict
+			break
+		}
+		value, err := su.readChild(fmt.Sprintf("[%d]", i))
+		if err != nil {
+			return nil, err
+		}
+		pdo.items = append(pdo.items, PyDictItem{key, value})
+	}
+	return pdo, nil
+}
+
+func (pdo *PyDictObject) GetItems() []PyDictItem {
+	return pdo.items
+}
+
+func (pdo *PyDictObject) w_object(m *Marshaler) error {
+	m.writeType(pdo, TYPE_DICT)
+	for _, item := range pdo.items {
+		if err := m.writeObject(item.Key); err != nil {
+			return err
+		}
+		if err := m.writeObject(item.Value); err != nil {
+			return err
+		}
+	}
+	m.writeByte(TYPE_NULL)
+	return m.err
+}
+
+// SetItems replaces the items of the dict
+func (pdo *PyDictObject) SetItems(items []PyDictItem) {
+	pdo.items = items
+}
+
+func NewDict(items ...PyDictItem) *PyDictObject {
+	return &PyDictObject{items: items}
+}
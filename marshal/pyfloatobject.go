@@ -0,0 +1,62 @@
+package marshal
+
+import (
+	"strconv"
+)
+
+type PyFloatObject struct {
+	refInfo
+	value    float64
+	text     string // original text of a TYPE_FLOAT
+	typecode byte
+}
+
+// readFloatString reads a float stored as text, as written by marshal
+// versions < 2
+func readFloatString(su *SimpleUnmarshaler) (float64, string, error) {
+	size, err := su.readByte("float size")
+	if err != nil {
+		return 0, "", err
+	}
+	buf, err := su.readBytes(int(size), "float")
+	if err != nil {
+		return 0, "", err
+	}
+	value, err := strconv.ParseFloat(string(buf), 64)
+	if err != nil {
+		return 0, "", su.errorf("%w: invalid float %q", ErrBadMarshalData, buf)
+	}
+	return value, string(buf), nil
+}
+
+func (pfo *PyFloatObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	var err error
+	if pfo.typecode == TYPE_FLOAT {
+		pfo.value, pfo.text, err = readFloatString(su)
+	} else {
+		pfo.value, err = su.readFloat64("binary float")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pfo, nil
+}
+
+func (pfo *PyFloatObject) GetValue() float64 {
+	return pfo.value
+}
+
+func (pfo *PyFloatObject) w_object(m *Marshaler) error {
+	if m.version < 2 || pfo.typecode == TYPE_FLOAT {
+		m.writeType(pfo, TYPE_FLOAT)
+		m.writeFloatText(pfo.value, pfo.text)
+	} else {
+		m.writeType(pfo, TYPE_BINARY_FLOAT)
+		m.writeFloat64(pfo.value)
+	}
+	return m.err
+}
+
+func NewFloat(value float64) *PyFloatObject {
+	return &PyFloatObject{value: value, typecode: TYPE_BINARY_FLOAT}
+}
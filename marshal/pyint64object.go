@@ -0,0 +1,33 @@
+package marshal
+
+import (
+	"encoding/binary"
+)
+
+// PyInt64Object is a Python 2 int which didn't fit in 32 bits, as written
+// by 64 bit builds
+type PyInt64Object struct {
+	refInfo
+	value int64
+}
+
+func (pio *PyInt64Object) r_object(su *SimpleUnmarshaler) (Object, error) {
+	buf, err := su.readBytes(8, "int64")
+	if err != nil {
+		return nil, err
+	}
+	pio.value = int64(binary.LittleEndian.Uint64(buf))
+	return pio, nil
+}
+
+func (pio *PyInt64Object) GetValue() int64 {
+	return pio.value
+}
+
+func (pio *PyInt64Object) w_object(m *Marshaler) error {
+	m.writeType(pio, TYPE_INT64)
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(pio.value))
+	m.write(buf)
+	return m.err
+}
@@ -1,25 +1,28 @@
 package marshal
 
-import (
-	"encoding/binary"
-	// "fmt"
-	"io"
-)
-
 type PyIntegerObject struct {
-	reader io.Reader
+	refInfo
 	value int32
 }
 
-func (pio *PyIntegerObject) r_object() _object {
-	if err := binary.Read(pio.reader, binary.LittleEndian, &pio.value); err != nil {
-		panic("Failed to read integer object")
+func (pio *PyIntegerObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	var err error
+	if pio.value, err = su.readInt32("integer"); err != nil {
+		return nil, err
 	}
-
-	// fmt.Println("integer_object, value=", pio.value)
-	return pio
+	return pio, nil
 }
 
 func (pio *PyIntegerObject) GetValue() int {
 	return int(pio.value)
-}
\ No newline at end of file
+}
+
+func (pio *PyIntegerObject) w_object(m *Marshaler) error {
+	m.writeType(pio, TYPE_INT)
+	m.writeInt32(pio.value)
+	return m.err
+}
+
+func NewInt(value int32) *PyIntegerObject {
+	return &PyIntegerObject{value: value}
+}
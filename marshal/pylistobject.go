@@ -1,42 +1,79 @@
 package marshal
 
 import (
-	"encoding/binary"
-	// "fmt"
-	"io"
+	"fmt"
 )
 
 type PyListObject struct {
-	reader   io.Reader
-	items    []_object
+	refInfo
+	items    []Object
 	typecode byte
 }
 
-func (plo *PyListObject) r_object() _object {
+func (plo *PyListObject) r_object(su *SimpleUnmarshaler) (Object, error) {
 	var nItems int
 	if plo.typecode == TYPE_SMALL_TUPLE {
-		var size int8
-		if err := binary.Read(plo.reader, binary.LittleEndian, &size); err != nil {
-			panic("Failed to read SMALL_TUPLE size")
+		size, err := su.readByte("small tuple size")
+		if err != nil {
+			return nil, err
 		}
 		nItems = int(size)
-		// fmt.Println("small_tuple, size=", nItems)
 	} else {
-		var size int32
-		if err := binary.Read(plo.reader, binary.LittleEndian, &size); err != nil {
-			panic("Failed to read size")
+		size, err := su.readSize("size")
+		if err != nil {
+			return nil, err
 		}
-		nItems = int(size)
-		// fmt.Println("list or tuple, size=", nItems)
+		nItems = size
 	}
 
 	for i := 0; i < nItems; i++ {
-		po := &PyObject{plo.reader}
-		plo.items = append(plo.items, po.r_object())
+		item, err := su.readChild(fmt.Sprintf("[%d]", i))
+		if err != nil {
+			return nil, err
+		}
+		plo.items = append(plo.items, item)
 	}
-	return plo
+	return plo, nil
 }
 
-func (plo *PyListObject) GetItems() []_object {
+func (plo *PyListObject) GetItems() []Object {
 	return plo.items
 }
+
+// IsTuple reports whether the object is a tuple rather than a list
+func (plo *PyListObject) IsTuple() bool {
+	return plo.typecode != TYPE_LIST
+}
+
+func (plo *PyListObject) w_object(m *Marshaler) error {
+	typecode := plo.typecode
+	if typecode == TYPE_SMALL_TUPLE && (m.version < 4 || len(plo.items) > 0xff) {
+		typecode = TYPE_TUPLE
+	}
+
+	m.writeType(plo, typecode)
+	if typecode == TYPE_SMALL_TUPLE {
+		m.writeByte(byte(len(plo.items)))
+	} else {
+		m.writeSize(len(plo.items))
+	}
+	for _, item := range plo.items {
+		if err := m.writeObject(item); err != nil {
+			return err
+		}
+	}
+	return m.err
+}
+
+// SetItems replaces the items of the list or tuple
+func (plo *PyListObject) SetItems(items []Object) {
+	plo.items = items
+}
+
+func NewTuple(items ...Object) *PyListObject {
+	return &PyListObject{items: items, typecode: TYPE_SMALL_TUPLE}
+}
+
+func NewList(items ...Object) *PyListObject {
+	return &PyListObject{items: items, typecode: TYPE_LIST}
+}
@@ -0,0 +1,100 @@
+package marshal
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+var ErrOverflow = errors.New("integer does not fit in int64")
+
+// PyLongObject is an arbitrary precision integer. Marshal stores these as a
+// signed digit count followed by base 2**15 digits, least significant first.
+type PyLongObject struct {
+	refInfo
+	value *big.Int
+}
+
+func (plo *PyLongObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	size, err := su.readInt32("long size")
+	if err != nil {
+		return nil, err
+	}
+	if size < -SIZE32_MAX || size > SIZE32_MAX {
+		return nil, su.errorf("%w: long size out of range", ErrBadMarshalData)
+	}
+	negative := size < 0
+	if negative {
+		size = -size
+	}
+
+	buf, err := su.readBytes(int(size)*2, "long digits")
+	if err != nil {
+		return nil, err
+	}
+
+	plo.value = new(big.Int)
+	digit := new(big.Int)
+	for i := int(size) - 1; i >= 0; i-- {
+		d := binary.LittleEndian.Uint16(buf[2*i:])
+		if d > PyLong_MARSHAL_MASK {
+			return nil, su.errorf("%w: digit out of range in long", ErrBadMarshalData)
+		}
+		if d == 0 && i == int(size)-1 {
+			return nil, su.errorf("%w: unnormalized long data", ErrBadMarshalData)
+		}
+		plo.value.Lsh(plo.value, PyLong_MARSHAL_SHIFT)
+		plo.value.Or(plo.value, digit.SetUint64(uint64(d)))
+	}
+	if negative {
+		plo.value.Neg(plo.value)
+	}
+	return plo, nil
+}
+
+// BigInt returns a copy of the value
+func (plo *PyLongObject) BigInt() *big.Int {
+	return new(big.Int).Set(plo.value)
+}
+
+// Int64 returns the value, or ErrOverflow if it doesn't fit in an int64
+func (plo *PyLongObject) Int64() (int64, error) {
+	if !plo.value.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return plo.value.Int64(), nil
+}
+
+// digits returns the base 2**15 digits of the absolute value, least
+// significant first
+func (plo *PyLongObject) digits() []uint16 {
+	var digits []uint16
+	abs := new(big.Int).Abs(plo.value)
+	mask := big.NewInt(PyLong_MARSHAL_MASK)
+	digit := new(big.Int)
+	for abs.Sign() != 0 {
+		digits = append(digits, uint16(digit.And(abs, mask).Uint64()))
+		abs.Rsh(abs, PyLong_MARSHAL_SHIFT)
+	}
+	return digits
+}
+
+func (plo *PyLongObject) w_object(m *Marshaler) error {
+	digits := plo.digits()
+	m.writeType(plo, TYPE_LONG)
+	if plo.value.Sign() < 0 {
+		m.writeSize(-len(digits))
+	} else {
+		m.writeSize(len(digits))
+	}
+	buf := make([]byte, 2*len(digits))
+	for i, digit := range digits {
+		binary.LittleEndian.PutUint16(buf[2*i:], digit)
+	}
+	m.write(buf)
+	return m.err
+}
+
+func NewLong(value *big.Int) *PyLongObject {
+	return &PyLongObject{value: new(big.Int).Set(value)}
+}
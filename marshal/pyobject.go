@@ -1,68 +1,135 @@
 package marshal
 
-import (
-	"encoding/binary"
-	// "fmt"
-	"io"
-)
-
-type PyObject struct {
-	reader io.Reader
-}
+type PyObject struct{}
 
-func (po *PyObject) r_object() _object {
-	var code byte
-	if err := binary.Read(po.reader, binary.LittleEndian, &code); err != nil {
-		panic("Failed to read code byte")
+func (po *PyObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	code, err := su.readByte("typecode")
+	if err != nil {
+		return nil, err
 	}
 
 	addRef := (code & FLAG_REF) != 0
 	typecode := code &^ FLAG_REF
-	// fmt.Printf("%c ", typecode)
-	var obj _object
+	su.typecodes = append(su.typecodes, typecode)
+	defer func() { su.typecodes = su.typecodes[:len(su.typecodes)-1] }()
+
+	var obj Object
 	var refPosition int
 	if addRef {
 		// reserve ref
-		refPosition = len(_unmarshaler.refs)
-		_unmarshaler.refs = append(_unmarshaler.refs, nil)
+		refPosition = len(su.refs)
+		su.refs = append(su.refs, nil)
 	}
 
 	switch typecode {
 	case TYPE_LIST, TYPE_TUPLE, TYPE_SMALL_TUPLE:
-		obj = &PyListObject{reader: po.reader, typecode: typecode}
-		obj.r_object()
+		obj = &PyListObject{typecode: typecode}
+		if addRef {
+			// Containers may refer to themselves
+			su.refs[refPosition] = obj
+		}
 
 	case TYPE_SHORT_ASCII, TYPE_SHORT_ASCII_INTERNED,
 		TYPE_STRING, TYPE_INTERNED, TYPE_UNICODE,
 		TYPE_ASCII, TYPE_ASCII_INTERNED:
-		obj = &PyStringObject{reader: po.reader, typecode: typecode}
-		obj.r_object()
+		obj = &PyStringObject{typecode: typecode, python2: su.pythonMajor == 2}
+
+	case TYPE_STRINGREF:
+		if su.pythonMajor != 2 {
+			return nil, su.errorf("%w", ErrUnknownTypecode)
+		}
+		n, err := su.readInt32("TYPE_STRINGREF index")
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || int(n) >= len(su.interned) {
+			return nil, su.errorf("%w: TYPE_STRINGREF index %d out of bounds", ErrBadMarshalData, n)
+		}
+		// Returning the interned object itself records the reference: the
+		// Marshaler writes a TYPE_STRINGREF for each occurrence after the first
+		return su.interned[n], nil
 
 	case TYPE_INT:
-		obj = &PyIntegerObject{reader: po.reader}
-		obj.r_object()
+		obj = &PyIntegerObject{}
+
+	case TYPE_INT64:
+		if su.pythonMajor != 2 {
+			return nil, su.errorf("%w", ErrUnknownTypecode)
+		}
+		obj = &PyInt64Object{}
+
+	case TYPE_NULL:
+		// Only valid as the terminator of a dict
+		return nil, nil
+
+	case TYPE_NONE:
+		obj = &PyNoneObject{}
+
+	case TYPE_TRUE, TYPE_FALSE:
+		obj = &PyBoolObject{value: typecode == TYPE_TRUE}
+
+	case TYPE_ELLIPSIS:
+		obj = &PyEllipsisObject{}
+
+	case TYPE_STOPITER:
+		obj = &PyStopIterObject{}
+
+	case TYPE_FLOAT, TYPE_BINARY_FLOAT:
+		obj = &PyFloatObject{typecode: typecode}
+
+	case TYPE_COMPLEX, TYPE_BINARY_COMPLEX:
+		obj = &PyComplexObject{typecode: typecode}
+
+	case TYPE_LONG:
+		obj = &PyLongObject{}
+
+	case TYPE_DICT:
+		obj = &PyDictObject{}
+		if addRef {
+			su.refs[refPosition] = obj
+		}
+
+	case TYPE_SET, TYPE_FROZENSET:
+		obj = &PySetObject{typecode: typecode}
+
+	case TYPE_SLICE:
+		obj = &PySliceObject{}
+
+	case TYPE_CODE:
+		obj = &PyCodeObject{
+			pythonMajor: su.pythonMajor,
+			pythonMinor: su.pythonMinor,
+		}
 
 	case TYPE_REF:
 		// Reference to a previous read
-		var n int32
-		if err := binary.Read(po.reader, binary.LittleEndian, &n); err != nil {
-			panic("Failed to read TYPE_REF")
+		n, err := su.readInt32("TYPE_REF index")
+		if err != nil {
+			return nil, err
 		}
 
-		if n < 0 || int(n) > len(_unmarshaler.refs) {
-			panic("TYPE_REF out of bounds")
+		if n < 0 || int(n) >= len(su.refs) {
+			return nil, su.errorf("%w: TYPE_REF index %d out of bounds", ErrBadMarshalData, n)
 		}
-
-		// fmt.Println("Get ref", n)
-		obj = _unmarshaler.refs[n]
+		if su.refs[n] == nil {
+			return nil, su.errorf("%w: TYPE_REF %d refers to an incomplete object", ErrBadMarshalData, n)
+		}
+		obj = su.refs[n]
+		if addRef {
+			su.refs[refPosition] = obj
+		}
+		return obj, nil
 
 	default:
-		panic("Unsupported typecode: " + string(typecode))
+		return nil, su.errorf("%w", ErrUnknownTypecode)
+	}
 
+	obj.ref().flagged = addRef
+	if _, err := obj.r_object(su); err != nil {
+		return nil, err
 	}
 	if addRef {
-		// fmt.Println("Added ref", len(_unmarshaler.refs))
-		_unmarshaler.refs[int32(refPosition)] = obj
+		su.refs[refPosition] = obj
 	}
-	return obj
+	return obj, nil
 }
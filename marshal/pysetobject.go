@@ -0,0 +1,54 @@
+package marshal
+
+import (
+	"fmt"
+)
+
+type PySetObject struct {
+	refInfo
+	items    []Object
+	typecode byte
+}
+
+func (pso *PySetObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	size, err := su.readSize("set size")
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < size; i++ {
+		item, err := su.readChild(fmt.Sprintf("<%d>", i))
+		if err != nil {
+			return nil, err
+		}
+		pso.items = append(pso.items, item)
+	}
+	return pso, nil
+}
+
+func (pso *PySetObject) GetItems() []Object {
+	return pso.items
+}
+
+func (pso *PySetObject) IsFrozen() bool {
+	return pso.typecode == TYPE_FROZENSET
+}
+
+func (pso *PySetObject) w_object(m *Marshaler) error {
+	m.writeType(pso, pso.typecode)
+	m.writeSize(len(pso.items))
+	for _, item := range pso.items {
+		if err := m.writeObject(item); err != nil {
+			return err
+		}
+	}
+	return m.err
+}
+
+func NewSet(items ...Object) *PySetObject {
+	return &PySetObject{items: items, typecode: TYPE_SET}
+}
+
+func NewFrozenSet(items ...Object) *PySetObject {
+	return &PySetObject{items: items, typecode: TYPE_FROZENSET}
+}
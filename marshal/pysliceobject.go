@@ -0,0 +1,52 @@
+package marshal
+
+import (
+	"fmt"
+)
+
+// PySliceObject represents a slice constant, marshalled since Python 3.14
+type PySliceObject struct {
+	refInfo
+	start Object
+	stop  Object
+	step  Object
+}
+
+func (pso *PySliceObject) r_object(su *SimpleUnmarshaler) (Object, error) {
+	var err error
+	if pso.start, err = su.readChild(".start"); err != nil {
+		return nil, err
+	}
+	if pso.stop, err = su.readChild(".stop"); err != nil {
+		return nil, err
+	}
+	if pso.step, err = su.readChild(".step"); err != nil {
+		return nil, err
+	}
+	return pso, nil
+}
+
+func (pso *PySliceObject) GetStart() Object {
+	return pso.start
+}
+
+func (pso *PySliceObject) GetStop() Object {
+	return pso.stop
+}
+
+func (pso *PySliceObject) GetStep() Object {
+	return pso.step
+}
+
+func (pso *PySliceObject) w_object(m *Marshaler) error {
+	if m.version < 5 {
+		return m.fail(fmt.Errorf("%w: slices need marshal version 5", ErrUnsupportedVersion))
+	}
+	m.writeType(pso, TYPE_SLICE)
+	for _, item := range []Object{pso.start, pso.stop, pso.step} {
+		if err := m.writeObject(item); err != nil {
+			return err
+		}
+	}
+	return m.err
+}
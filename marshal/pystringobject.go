@@ -1,50 +1,124 @@
 package marshal
 
-import (
-	"encoding/binary"
-	// "fmt"
-	"io"
-)
-
 type PyStringObject struct {
-	reader   io.Reader
+	refInfo
 	value    string
 	typecode byte
+	python2  bool // TYPE_INTERNED holds bytes rather than text in Python 2
 }
 
-func (pso *PyStringObject) r_object() _object {
+func (pso *PyStringObject) r_object(su *SimpleUnmarshaler) (Object, error) {
 	var length int
 
 	switch pso.typecode {
 	case TYPE_SHORT_ASCII, TYPE_SHORT_ASCII_INTERNED:
-		var size uint8
-		err := binary.Read(pso.reader, binary.LittleEndian, &size)
+		size, err := su.readByte("string size")
 		if err != nil {
-			return nil
+			return nil, err
 		}
 		length = int(size)
 
 	case TYPE_STRING, TYPE_INTERNED, TYPE_UNICODE,
 		TYPE_ASCII, TYPE_ASCII_INTERNED:
-		var size int32
-		err := binary.Read(pso.reader, binary.LittleEndian, &size)
+		size, err := su.readSize("string size")
 		if err != nil {
-			return nil
+			return nil, err
 		}
-		length = int(size)
+		length = size
 	}
 
-	buf := make([]byte, length)
-	_, err := io.ReadFull(pso.reader, buf)
+	buf, err := su.readBytes(length, "string")
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
 	pso.value = string(buf)
-	// fmt.Println("string_object, value=", pso.value)
-	return pso
+	if pso.python2 && pso.typecode == TYPE_INTERNED {
+		su.interned = append(su.interned, pso)
+	}
+	return pso, nil
 }
 
-func(pso *PyStringObject) GetString() string {
+func (pso *PyStringObject) GetString() string {
 	return pso.value
 }
+
+// GetBytes returns the raw contents, which for TYPE_STRING (and Python 2
+// TYPE_INTERNED) are bytes rather than text
+func (pso *PyStringObject) GetBytes() []byte {
+	return []byte(pso.value)
+}
+
+func (pso *PyStringObject) IsBytes() bool {
+	return pso.typecode == TYPE_STRING || pso.python2 && pso.typecode == TYPE_INTERNED
+}
+
+func (pso *PyStringObject) w_object(m *Marshaler) error {
+	typecode := pso.typecode
+	if pso.python2 && typecode == TYPE_INTERNED {
+		// Python 2 writes an interned string once and refers back to it by
+		// its index later. TYPE_STRINGREF decodes to the object read for
+		// TYPE_INTERNED, so the original order is reproduced.
+		if m.version == 0 {
+			typecode = TYPE_STRING
+		} else if idx, ok := m.interned[pso]; ok {
+			m.writeByte(TYPE_STRINGREF)
+			m.writeInt32(int32(idx))
+			return m.err
+		} else {
+			m.interned[pso] = len(m.interned)
+		}
+	}
+	if m.version < 4 {
+		// The compact ASCII types were added in version 4
+		switch typecode {
+		case TYPE_SHORT_ASCII, TYPE_ASCII:
+			typecode = TYPE_UNICODE
+		case TYPE_SHORT_ASCII_INTERNED, TYPE_ASCII_INTERNED:
+			typecode = TYPE_INTERNED
+		}
+	}
+	if len(pso.value) > 0xff {
+		switch typecode {
+		case TYPE_SHORT_ASCII:
+			typecode = TYPE_ASCII
+		case TYPE_SHORT_ASCII_INTERNED:
+			typecode = TYPE_ASCII_INTERNED
+		}
+	}
+
+	m.writeType(pso, typecode)
+	if typecode == TYPE_SHORT_ASCII || typecode == TYPE_SHORT_ASCII_INTERNED {
+		m.writeByte(byte(len(pso.value)))
+	} else {
+		m.writeSize(len(pso.value))
+	}
+	m.write([]byte(pso.value))
+	return m.err
+}
+
+// NewString returns a str object, using the compact ASCII types when possible
+func NewString(value string) *PyStringObject {
+	typecode := byte(TYPE_UNICODE)
+	if isASCII(value) {
+		typecode = TYPE_ASCII
+		if len(value) <= 0xff {
+			typecode = TYPE_SHORT_ASCII
+		}
+	}
+	return &PyStringObject{value: value, typecode: typecode}
+}
+
+// NewBytes returns a bytes object
+func NewBytes(value []byte) *PyStringObject {
+	return &PyStringObject{value: string(value), typecode: TYPE_STRING}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+# -*- coding: utf-8 -*-
+# Source of the sample.*.pyc fixtures, compiled by each Python version
+import os
+
+NUMBERS = (0, -1, 1 << 40, -(1 << 100), 1.5, float("inf"), 2j, 1e300 * 1j)
+TEXT = (u"text", u"été", u"\U0001f600", b"bytes", "name", "name")
+FLAGS = frozenset(["a", "b", "c"])
+
+
+class Sample(object):
+    """Docstring"""
+
+    limit = 10
+
+    def __init__(self, name, *args, **kwargs):
+        self.name = name
+        self.args = args
+        self.kwargs = kwargs
+
+    def method(self, value=None):
+        if value in (1, 2, 3) or value in {"a", "b"}:
+            return [x * 2 for x in range(self.limit) if x % 2]
+        try:
+            return {k: v for k, v in self.kwargs.items()}
+        except (KeyError, ValueError) as e:
+            raise RuntimeError(str(e))
+        finally:
+            self.name = os.path.join(self.name, "name")
+
+
+def closure(a, b=2):
+    def inner(c):
+        return a + b + c
+
+    return inner, lambda: inner(a)[::-1]
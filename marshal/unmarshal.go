@@ -1,30 +1,176 @@
 package marshal
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"strings"
 )
 
-type SimpleUnmarshaler struct {
-	reader io.Reader
-	refs []_object
+// Maximum nesting of containers, matching CPython's MAX_MARSHAL_STACK_DEPTH
+const MAX_MARSHAL_STACK_DEPTH = 2000
+
+// UnmarshalError describes malformed marshal data
+type UnmarshalError struct {
+	Offset   int64  // offset of the byte where decoding failed
+	Typecode byte   // typecode of the innermost object being decoded, 0 if none
+	Path     string // nesting path of the object, e.g. [3].co_consts[1]
+	Err      error
+}
+
+func (e *UnmarshalError) Error() string {
+	msg := fmt.Sprintf("marshal: %v at offset %d", e.Err, e.Offset)
+	if e.Typecode != 0 {
+		msg += fmt.Sprintf(" (typecode %q)", e.Typecode)
+	}
+	if e.Path != "" {
+		msg += " in " + e.Path
+	}
+	return msg
+}
+
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	ErrBadMarshalData  = errors.New("bad marshal data")
+	ErrUnexpectedEOF   = errors.New("unexpected end of data")
+	ErrUnknownTypecode = errors.New("unknown typecode")
+)
+
+// countingReader keeps track of the offset for error messages
+type countingReader struct {
+	r      io.Reader
+	offset int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.offset += int64(n)
+	return n, err
 }
 
-var _unmarshaler *SimpleUnmarshaler
+type SimpleUnmarshaler struct {
+	reader      *countingReader
+	refs        []Object
+	interned    []Object // interned strings, for TYPE_STRINGREF
+	pythonMajor int
+	pythonMinor int
+	typecodes   []byte
+	path        []string
+}
 
 func NewUnmarshaler(r io.Reader) *SimpleUnmarshaler {
-	_unmarshaler = &SimpleUnmarshaler{reader: r}
-	return _unmarshaler
+	return NewUnmarshalerForVersion(r, DEFAULT_PYTHON_MAJOR, DEFAULT_PYTHON_MINOR)
 }
 
-func (su *SimpleUnmarshaler) Unmarshal() _object {
-	defer func() {
-        if r := recover(); r != nil {
-            fmt.Println("Panicked during unmarshal!")
-			fmt.Println(r)
-        }
-    }()
+// NewUnmarshalerForVersion returns an unmarshaler which decodes code objects
+// using the layout of the given Python version
+func NewUnmarshalerForVersion(r io.Reader, major, minor int) *SimpleUnmarshaler {
+	return &SimpleUnmarshaler{reader: &countingReader{r: r}, pythonMajor: major, pythonMinor: minor}
+}
+
+// Unmarshal reads the next object. Malformed data is reported as an
+// *UnmarshalError.
+func (su *SimpleUnmarshaler) Unmarshal() (Object, error) {
+	su.refs = nil
+	su.interned = nil
+	su.typecodes = nil
+	su.path = nil
+
+	obj, err := su.readObject()
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, su.errorf("%w: NULL object", ErrBadMarshalData)
+	}
+	return obj, nil
+}
 
-	pobj := PyObject{reader: su.reader}
-	return pobj.r_object()
-}
\ No newline at end of file
+// readObject reads the next object. Each SimpleUnmarshaler owns its ref
+// table, so independent unmarshalers may be used concurrently.
+func (su *SimpleUnmarshaler) readObject() (Object, error) {
+	if len(su.typecodes) >= MAX_MARSHAL_STACK_DEPTH {
+		return nil, su.errorf("%w: nesting too deep", ErrBadMarshalData)
+	}
+	pobj := PyObject{}
+	return pobj.r_object(su)
+}
+
+// readChild reads a nested object which must not be NULL, recording its
+// position in the nesting path
+func (su *SimpleUnmarshaler) readChild(pathElem string) (Object, error) {
+	su.path = append(su.path, pathElem)
+	obj, err := su.readObject()
+	if err == nil && obj == nil {
+		err = su.errorf("%w: NULL object", ErrBadMarshalData)
+	}
+	su.path = su.path[:len(su.path)-1]
+	return obj, err
+}
+
+func (su *SimpleUnmarshaler) errorf(format string, args ...any) error {
+	e := &UnmarshalError{
+		Offset: su.reader.offset,
+		Path:   strings.Join(su.path, ""),
+		Err:    fmt.Errorf(format, args...),
+	}
+	if len(su.typecodes) != 0 {
+		e.Typecode = su.typecodes[len(su.typecodes)-1]
+	}
+	return e
+}
+
+func (su *SimpleUnmarshaler) eofError(what string) error {
+	return su.errorf("%w reading %s", ErrUnexpectedEOF, what)
+}
+
+func (su *SimpleUnmarshaler) readByte(what string) (byte, error) {
+	var value byte
+	if err := binary.Read(su.reader, binary.LittleEndian, &value); err != nil {
+		return 0, su.eofError(what)
+	}
+	return value, nil
+}
+
+func (su *SimpleUnmarshaler) readInt32(what string) (int32, error) {
+	var value int32
+	if err := binary.Read(su.reader, binary.LittleEndian, &value); err != nil {
+		return 0, su.eofError(what)
+	}
+	return value, nil
+}
+
+func (su *SimpleUnmarshaler) readFloat64(what string) (float64, error) {
+	var value uint64
+	if err := binary.Read(su.reader, binary.LittleEndian, &value); err != nil {
+		return 0, su.eofError(what)
+	}
+	return math.Float64frombits(value), nil
+}
+
+// readSize reads a 32 bit size, which must be non negative
+func (su *SimpleUnmarshaler) readSize(what string) (int, error) {
+	size, err := su.readInt32(what)
+	if err != nil {
+		return 0, err
+	}
+	if size < 0 {
+		return 0, su.errorf("%w: %s out of range", ErrBadMarshalData, what)
+	}
+	return int(size), nil
+}
+
+// readBytes reads n bytes without trusting n for the allocation size, as it
+// comes from untrusted input
+func (su *SimpleUnmarshaler) readBytes(n int, what string) ([]byte, error) {
+	buf, err := io.ReadAll(io.LimitReader(su.reader, int64(n)))
+	if err != nil || len(buf) != n {
+		return nil, su.eofError(what)
+	}
+	return buf, nil
+}
@@ -0,0 +1,220 @@
+package marshal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func unmarshal(data []byte, major, minor int) (Object, error) {
+	return NewUnmarshalerForVersion(bytes.NewReader(data), major, minor).Unmarshal()
+}
+
+// nested returns depth single item lists around None
+func nested(depth int) []byte {
+	return []byte(strings.Repeat("[\x01\x00\x00\x00", depth) + "N")
+}
+
+func TestUnmarshalTypecodes(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"None", "N", "*marshal.PyNoneObject"},
+		{"True", "T", "*marshal.PyBoolObject"},
+		{"Ellipsis", ".", "*marshal.PyEllipsisObject"},
+		{"StopIteration", "S", "*marshal.PyStopIterObject"},
+		{"float", "f\x031.5", "*marshal.PyFloatObject"},
+		{"binary float", "g\x00\x00\x00\x00\x00\x00\xf8\x3f", "*marshal.PyFloatObject"},
+		{"complex", "x\x011\x012", "*marshal.PyComplexObject"},
+		{"binary complex", "y\x00\x00\x00\x00\x00\x00\xf8\x3f\x00\x00\x00\x00\x00\x00\x00\x40", "*marshal.PyComplexObject"},
+		{"long", "l\xfe\xff\xff\xff\x01\x00\x02\x00", "*marshal.PyLongObject"},
+		{"dict", "{z\x01aN0", "*marshal.PyDictObject"},
+		{"set", "<\x01\x00\x00\x00N", "*marshal.PySetObject"},
+		{"frozenset", ">\x00\x00\x00\x00", "*marshal.PySetObject"},
+		{"slice", ":NNN", "*marshal.PySliceObject"},
+		{"small tuple", ")\x01N", "*marshal.PyListObject"},
+		{"ascii", "a\x02\x00\x00\x00ab", "*marshal.PyStringObject"},
+		// A list holding itself
+		{"ref", "\xdb\x01\x00\x00\x00r\x00\x00\x00\x00", "*marshal.PyListObject"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			obj, err := unmarshal([]byte(test.data), 3, 11)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%T", obj); got != test.want {
+				t.Errorf("decoded %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		major  int
+		err    error
+		offset int64
+	}{
+		{"empty", "", 3, ErrUnexpectedEOF, 0},
+		{"truncated int", "i\x01\x00", 3, ErrUnexpectedEOF, 3},
+		{"truncated string", "s\x05\x00\x00\x00ab", 3, ErrUnexpectedEOF, 7},
+		{"truncated tuple", ")\x02N", 3, ErrUnexpectedEOF, 3},
+		{"negative size", "s\xff\xff\xff\xff", 3, ErrBadMarshalData, 5},
+		{"unknown typecode", "?", 3, ErrUnknownTypecode, 1},
+		{"NULL", "0", 3, ErrBadMarshalData, 1},
+		{"NULL item", "[\x01\x00\x00\x000", 3, ErrBadMarshalData, 6},
+		{"ref out of bounds", "r\x00\x00\x00\x00", 3, ErrBadMarshalData, 5},
+		{"negative ref", "\xdb\x01\x00\x00\x00r\xff\xff\xff\xff", 3, ErrBadMarshalData, 10},
+		{"ref to incomplete set", "\xbc\x01\x00\x00\x00r\x00\x00\x00\x00", 3, ErrBadMarshalData, 10},
+		{"stringref in Python 3", "R\x00\x00\x00\x00", 3, ErrUnknownTypecode, 1},
+		{"stringref out of bounds", "(\x02\x00\x00\x00t\x01\x00\x00\x00aR\x01\x00\x00\x00", 2, ErrBadMarshalData, 16},
+		{"too deep", string(nested(MAX_MARSHAL_STACK_DEPTH + 1)), 3, ErrBadMarshalData, 5 * MAX_MARSHAL_STACK_DEPTH},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := unmarshal([]byte(test.data), test.major, 7)
+			var uerr *UnmarshalError
+			if !errors.As(err, &uerr) || !errors.Is(err, test.err) {
+				t.Fatalf("err = %v, want %v", err, test.err)
+			}
+			if uerr.Offset != test.offset {
+				t.Errorf("offset = %d, want %d", uerr.Offset, test.offset)
+			}
+		})
+	}
+
+	if _, err := unmarshal(nested(MAX_MARSHAL_STACK_DEPTH-1), 3, 7); err != nil {
+		t.Errorf("nesting below the limit: %v", err)
+	}
+	// Containers may hold themselves
+	obj, err := unmarshal([]byte("\xdb\x01\x00\x00\x00r\x00\x00\x00\x00"), 3, 7)
+	if items, ok := AsSequence(obj); err != nil || !ok || items[0] != obj {
+		t.Errorf("self-referencing list = %v, %v", obj, err)
+	}
+}
+
+func TestUnmarshalTruncated(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.3.11.pyc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = data[16:]
+	for n := 0; n < len(data); n++ {
+		if _, err := unmarshal(data[:n], 3, 11); !errors.Is(err, ErrUnexpectedEOF) {
+			t.Fatalf("%d of %d bytes: err = %v", n, len(data), err)
+		}
+	}
+}
+
+// FuzzUnmarshal decodes and re-encodes arbitrary input, which must give back
+// an object decoding to the same bytes again
+func FuzzUnmarshal(f *testing.F) {
+	for _, seed := range []struct {
+		file   string
+		major  byte
+		header int
+	}{
+		{"testdata/sample.2.7.pyc", 2, 8},
+		{"testdata/sample.3.11.pyc", 3, 16},
+		{"../disasm/testdata/cyclic.pyc", 3, 16},
+	} {
+		data, err := os.ReadFile(seed.file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(seed.major, data[seed.header:])
+	}
+	f.Add(byte(3), nested(MAX_MARSHAL_STACK_DEPTH+1))
+	f.Add(byte(3), []byte("\xdb\x01\x00\x00\x00r\x00\x00\x00\x00"))
+
+	f.Fuzz(func(t *testing.T, major byte, data []byte) {
+		major = 2 + major%2
+		minor, version := 11, 4
+		if major == 2 {
+			minor, version = 7, 2
+		}
+		obj, err := unmarshal(data, int(major), minor)
+		if err != nil {
+			return
+		}
+		out, err := Marshal(obj, version)
+		if err != nil {
+			return
+		}
+		obj, err = unmarshal(out, int(major), minor)
+		if err != nil {
+			t.Fatalf("re-encoded data fails to decode: %v", err)
+		}
+		again, err := Marshal(obj, version)
+		if err != nil || !bytes.Equal(again, out) {
+			t.Fatalf("re-encoding isn't stable: %v", err)
+		}
+	})
+}
+
+func TestUnmarshalConcurrent(t *testing.T) {
+	// Each unmarshaler owns its ref table, so decodes running in parallel
+	// must match a sequential one. Run with -race.
+	files, err := filepath.Glob("testdata/sample.3.*.pyc")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no fixtures: %v", err)
+	}
+	type sample struct {
+		data         []byte
+		major, minor int
+		want         Object
+	}
+	var samples []sample
+	for _, file := range files {
+		var minor int
+		fmt.Sscanf(filepath.Base(file), "sample.3.%d.pyc", &minor)
+		header := 16
+		if minor < 7 {
+			header = 12
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := unmarshal(data[header:], 3, minor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, sample{data[header:], 3, minor, want})
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8*len(samples))
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := range samples {
+				s := samples[(i+j)%len(samples)]
+				got, err := unmarshal(s.data, s.major, s.minor)
+				if err == nil && !reflect.DeepEqual(got, s.want) {
+					err = fmt.Errorf("3.%d decoded differently", s.minor)
+				}
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,479 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-restruct/restruct"
+)
+
+// Archive is a PyInstaller CArchive embedded in an executable.
+type Archive struct {
+	// Name is the name of the input file, used for messages and for naming
+	// the extraction directory.
+	Name string
+
+	// Logf, when set, receives progress messages. Messages carry the usual
+	// "[+]" / "[!]" prefixes but no trailing newline.
+	Logf func(format string, args ...any)
+
+	// Disassemble makes ExtractFiles write a dis style listing next to every
+	// extracted pyc, with the .dis extension.
+	Disassemble bool
+
+	// Key decrypts encrypted PYZ archives. When nil, the key is recovered
+	// from the pyimod00_crypto_key module of the CArchive.
+	Key []byte
+
+	// Siblings are the other executables of a MERGE build, searched for the
+	// dependencies of this one before the directory of Name.
+	Siblings []string
+
+	// OnedirRoot is the folder of a onedir build holding the executable.
+	// When set, ExtractFiles also copies the other files of the folder and
+	// writes ONEDIR_MANIFEST.
+	OnedirRoot string
+
+	// NestingDepth makes ExtractFiles also extract the PyInstaller
+	// executables found among the extracted files, up to this many levels
+	// deep. Zero disables it.
+	NestingDepth int
+
+	// Heuristic makes CheckFile look for cookie-shaped structures when
+	// PYINST_MAGIC isn't found, recovering archives whose magic was patched.
+	Heuristic bool
+
+	r                       io.ReaderAt
+	closer                  io.Closer
+	fileSize                int64
+	cookiePosition          int64
+	archiveEnd              int64
+	machOSlices             []MachOSlice
+	trailingSize            int64
+	pe                      *PEInfo
+	pyInstVersion           int64
+	pythonMajorVersion      int
+	pythonMinorVersion      int
+	pythonLibName           string
+	overlaySize             int64
+	overlayPosition         int64
+	tableOfContentsSize     int64
+	tableOfContentsPosition int64
+	tableOfContents         []CTOCEntry
+	pycMagic                [4]byte
+	gotPycMagic             bool
+	barePycsList            []*barePyc
+	out                     Sink
+	keySearched             bool
+	recoveredKey            []byte
+	pyzCipher               pyzCipher
+	symlinks                []Symlink
+	links                   map[string]string
+	unzippedSize            int64
+	unwrittenSymlinks       []Symlink
+	splash                  *SplashScreen
+	runtimeOptions          []RuntimeOption
+	dependencies            []Dependency
+	extractedFiles          []ExtractedFile
+	fileSource              string
+	nestingLevel            int
+	outPrefix               string
+	nestedCandidates        []nestedCandidate
+	nested                  []*Archive
+	seen                    map[[sha256.Size]byte]string
+}
+
+// barePyc is a pyc whose header can only be written once the pyc magic is
+// known.
+type barePyc struct {
+	filepath string
+	contents []byte
+}
+
+// NewArchive returns an Archive reading size bytes from r.
+func NewArchive(r io.ReaderAt, size int64, name string) *Archive {
+	return &Archive{Name: name, r: r, fileSize: size, cookiePosition: -1}
+}
+
+// Open opens the file at path for extraction. The returned Archive must be
+// closed by the caller.
+func Open(path string) (*Archive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open %s: %w", path, err)
+	}
+	fileInfo, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("couldn't get size of file %s: %w", path, err)
+	}
+	a := NewArchive(f, fileInfo.Size(), path)
+	a.closer = f
+	return a, nil
+}
+
+func (p *Archive) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+	return p.closer.Close()
+}
+
+func (p *Archive) logf(format string, args ...any) {
+	if p.Logf != nil {
+		p.Logf(format, args...)
+	}
+}
+
+// readAt reads exactly n bytes at offset off.
+func (p *Archive) readAt(off, n int64) ([]byte, error) {
+	if off < 0 || n < 0 || off+n > p.fileSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := make([]byte, n)
+	if _, err := p.r.ReadAt(data, off); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
+
+// PythonVersion returns the Python version recorded in the cookie.
+func (p *Archive) PythonVersion() (major, minor int) {
+	return p.pythonMajorVersion, p.pythonMinorVersion
+}
+
+// PyInstVersion returns 20 for PyInstaller 2.0 archives and 21 for 2.1+.
+func (p *Archive) PyInstVersion() int64 {
+	return p.pyInstVersion
+}
+
+// PythonLibName returns the python library name stored in 2.1+ cookies.
+func (p *Archive) PythonLibName() string {
+	return p.pythonLibName
+}
+
+// TableOfContents returns the entries found by ParseTOC.
+func (p *Archive) TableOfContents() []CTOCEntry {
+	return p.tableOfContents
+}
+
+// RuntimeOption is an ARCHIVE_ITEM_RUNTIME_OPTION entry, an interpreter or
+// bootloader option such as "W ignore" or "pyi-contents-directory _internal".
+type RuntimeOption struct {
+	Name string
+	// Value is empty for flags such as "u"
+	Value string
+}
+
+func (o RuntimeOption) String() string {
+	if o.Value == "" {
+		return o.Name
+	}
+	return o.Name + " " + o.Value
+}
+
+// RuntimeOptions returns the runtime options found by ParseTOC, in archive
+// order.
+func (p *Archive) RuntimeOptions() []RuntimeOption {
+	return p.runtimeOptions
+}
+
+func (p *Archive) CheckFile() error {
+	p.logf("[+] Processing %s", p.Name)
+
+	p.cookiePosition = -1
+	p.archiveEnd = p.fileSize
+	p.trailingSize = 0
+	p.pyInstVersion = 0
+
+	if p.fileSize < int64(len(PYINST_MAGIC)) {
+		return ErrTooShort
+	}
+
+	// Linux bootloaders may carry the archive in an ELF section rather than
+	// appended to the file
+	if offset, size, ok := p.elfSection(PYDATA_SECTION); ok {
+		cookiePosition, version, err := p.locateCookie(offset, offset+size)
+		if err != nil {
+			return err
+		}
+		if cookiePosition != -1 {
+			p.logf("[+] Found archive in ELF section %s at offset %d", PYDATA_SECTION, offset)
+			p.cookiePosition, p.pyInstVersion = cookiePosition, version
+			p.archiveEnd = offset + size
+		}
+	}
+
+	// Signed PE files end with the certificate table, which follows the
+	// archive unless it was appended after signing
+	p.pe = p.readPE()
+	if p.cookiePosition == -1 && p.pe != nil && p.pe.CertificateSize != 0 {
+		cookiePosition, version, err := p.locateCookie(0, p.pe.CertificateOffset)
+		if err != nil {
+			return err
+		}
+		if cookiePosition != -1 {
+			p.cookiePosition, p.pyInstVersion = cookiePosition, version
+			p.archiveEnd = p.pe.CertificateOffset
+		}
+	}
+
+	// Mach-O files may be universal binaries carrying the archive in one
+	// of their slices, followed by a code signature
+	p.machOSlices = p.readMachOSlices()
+	for i := range p.machOSlices {
+		slice := &p.machOSlices[i]
+		cookiePosition, version, err := p.locateCookie(slice.Offset, slice.Offset+slice.Size)
+		if err != nil {
+			return err
+		}
+		if cookiePosition == -1 {
+			continue
+		}
+		slice.HasArchive = true
+		p.logf("[+] Found archive in Mach-O slice %s at offset %d", slice.Cpu, slice.Offset)
+		if p.cookiePosition == -1 {
+			p.cookiePosition, p.pyInstVersion = cookiePosition, version
+			p.archiveEnd = slice.Offset + slice.Size
+		}
+	}
+
+	if p.cookiePosition == -1 {
+		cookiePosition, version, err := p.locateCookie(0, p.fileSize)
+		if err != nil {
+			return err
+		}
+		p.cookiePosition, p.pyInstVersion = cookiePosition, version
+	}
+	if p.cookiePosition == -1 {
+		return ErrMissingCookie
+	}
+
+	// Cookies found by their structure already have a version
+	if p.pyInstVersion == 0 {
+		cookie, err := p.readAt(p.cookiePosition+PYINST20_COOKIE_SIZE, 64)
+		if err != nil {
+			// Not enough room for a 2.1+ cookie
+			cookie = nil
+		}
+
+		cookie = bytes.ToLower(cookie)
+		if bytes.Contains(cookie, []byte("python")) {
+			p.pyInstVersion = 21
+		} else {
+			p.pyInstVersion = 20
+		}
+	}
+	if p.pyInstVersion == 21 {
+		p.logf("[+] Pyinstaller version: 2.1+")
+	} else {
+		p.logf("[+] Pyinstaller version: 2.0")
+	}
+	return nil
+}
+
+// findCookie returns the position of PYINST_MAGIC between start and end, or
+// -1, searching backwards chunk by chunk.
+func (p *Archive) findCookie(start, end int64) (int64, error) {
+	const searchChunkSize = 8192
+	endPosition := end
+	for {
+		startPosition := max(endPosition-searchChunkSize, start)
+		if endPosition-startPosition < int64(len(PYINST_MAGIC)) {
+			return -1, nil
+		}
+
+		data, err := p.readAt(startPosition, endPosition-startPosition)
+		if err != nil {
+			return -1, fmt.Errorf("file read failed: %w", err)
+		}
+
+		if offs := bytes.Index(data, PYINST_MAGIC[:]); offs != -1 {
+			return startPosition + int64(offs), nil
+		}
+		endPosition = startPosition + int64(len(PYINST_MAGIC)) - 1
+
+		if startPosition == start {
+			return -1, nil
+		}
+	}
+}
+
+// isPadding reports whether the size bytes at off are zeros aligning the
+// next structure, which code signing tools add after the archive
+func (p *Archive) isPadding(off, size int64) bool {
+	if size >= 16 {
+		return false
+	}
+	data, err := p.readAt(off, size)
+	return err == nil && bytes.Count(data, []byte{0}) == len(data)
+}
+
+// TrailingData returns the position and size of the data following the
+// cookie which isn't part of the archive.
+func (p *Archive) TrailingData() (offset, size int64) {
+	return p.archiveEnd - p.trailingSize, p.trailingSize
+}
+
+func (p *Archive) GetCArchiveInfo() error {
+	if p.cookiePosition == -1 {
+		return ErrNotParsed
+	}
+
+	getPyMajMinVersion := func(version int) (int, int) {
+		if version >= 100 {
+			return version / 100, version % 100
+		}
+		return version / 10, version % 10
+	}
+
+	printPythonVerLenPkg := func(pyMajVer, pyMinVer int, lenPkg uint) {
+		p.logf("[+] Python version: %d.%d", pyMajVer, pyMinVer)
+		p.logf("[+] Length of package: %d bytes", lenPkg)
+	}
+
+	calculateTocPosition := func(cookieSize int, lengthOfPackage, toc uint, tocLen int) error {
+		// The package ends with the cookie, anything following it up to the
+		// end of the file, the pydata section or the signature was added
+		// afterwards
+		cookieEnd := p.cookiePosition + int64(cookieSize)
+		p.trailingSize = max(p.archiveEnd-cookieEnd, 0)
+		if p.trailingSize > 0 && !p.isPadding(cookieEnd, p.trailingSize) {
+			p.logf("[!] Warning: %d bytes appended after the cookie at offset %d", p.trailingSize, cookieEnd)
+		}
+
+		// Overlay is the data appended at the end of the PE
+		p.overlaySize = int64(lengthOfPackage)
+		p.overlayPosition = cookieEnd - p.overlaySize
+		p.tableOfContentsPosition = p.overlayPosition + int64(toc)
+		p.tableOfContentsSize = int64(tocLen)
+
+		if p.overlayPosition < 0 || p.tableOfContentsSize < 0 ||
+			p.tableOfContentsPosition+p.tableOfContentsSize > cookieEnd {
+			return ErrNotPyInstaller
+		}
+		p.checkPEOverlay()
+		return nil
+	}
+
+	if p.pyInstVersion == 20 {
+		var pyInst20Cookie PyInst20Cookie
+		cookieBuf, err := p.readAt(p.cookiePosition, PYINST20_COOKIE_SIZE)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrNotPyInstaller, err)
+		}
+
+		if err := restruct.Unpack(cookieBuf, binary.LittleEndian, &pyInst20Cookie); err != nil {
+			return fmt.Errorf("%w: %v", ErrNotPyInstaller, err)
+		}
+
+		p.pythonMajorVersion, p.pythonMinorVersion = getPyMajMinVersion(pyInst20Cookie.PythonVersion)
+		printPythonVerLenPkg(p.pythonMajorVersion, p.pythonMinorVersion, uint(pyInst20Cookie.LengthOfPackage))
+
+		return calculateTocPosition(
+			PYINST20_COOKIE_SIZE,
+			uint(pyInst20Cookie.LengthOfPackage),
+			uint(pyInst20Cookie.Toc),
+			pyInst20Cookie.TocLen,
+		)
+	}
+
+	var pyInst21Cookie PyInst21Cookie
+	cookieBuf, err := p.readAt(p.cookiePosition, PYINST21_COOKIE_SIZE)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotPyInstaller, err)
+	}
+	if err := restruct.Unpack(cookieBuf, binary.LittleEndian, &pyInst21Cookie); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotPyInstaller, err)
+	}
+	p.pythonLibName = string(bytes.Trim(pyInst21Cookie.PythonLibName, "\x00"))
+	p.logf("[+] Python library file: %s", p.pythonLibName)
+	p.pythonMajorVersion, p.pythonMinorVersion = getPyMajMinVersion(pyInst21Cookie.PythonVersion)
+	printPythonVerLenPkg(p.pythonMajorVersion, p.pythonMinorVersion, pyInst21Cookie.LengthOfPackage)
+
+	return calculateTocPosition(
+		PYINST21_COOKIE_SIZE,
+		pyInst21Cookie.LengthOfPackage,
+		pyInst21Cookie.Toc,
+		pyInst21Cookie.TocLen,
+	)
+}
+
+func (p *Archive) ParseTOC() error {
+	if p.cookiePosition == -1 {
+		return ErrNotParsed
+	}
+
+	p.tableOfContents = nil
+	p.runtimeOptions = nil
+	p.dependencies = nil
+	var parsedLen int64 = 0
+
+	// Parse table of contents
+	for parsedLen < p.tableOfContentsSize {
+		var ctocEntry CTOCEntry
+
+		data, err := p.readAt(p.tableOfContentsPosition+parsedLen, CTOC_ENTRY_SIZE)
+		if err != nil {
+			return fmt.Errorf("%w: entry at offset %d: %v", ErrInvalidTOC, parsedLen, err)
+		}
+		if err := restruct.Unpack(data, binary.LittleEndian, &ctocEntry); err != nil {
+			return fmt.Errorf("%w: entry at offset %d: %v", ErrInvalidTOC, parsedLen, err)
+		}
+		if ctocEntry.EntrySize < CTOC_ENTRY_SIZE {
+			return fmt.Errorf("%w: entry at offset %d has size %d", ErrInvalidTOC, parsedLen, ctocEntry.EntrySize)
+		}
+
+		nameBuffer, err := p.readAt(p.tableOfContentsPosition+parsedLen+CTOC_ENTRY_SIZE, int64(ctocEntry.EntrySize-CTOC_ENTRY_SIZE))
+		if err != nil {
+			return fmt.Errorf("%w: entry at offset %d: %v", ErrInvalidTOC, parsedLen, err)
+		}
+
+		nameBuffer = bytes.TrimRight(nameBuffer, "\x00")
+		if len(nameBuffer) == 0 {
+			ctocEntry.Name = randomString()
+			p.logf("[!] Warning: Found an unamed file in CArchive. Using random name %s", ctocEntry.Name)
+		} else {
+			ctocEntry.Name = string(nameBuffer)
+		}
+
+		if ctocEntry.TypeCompressedData == 'o' {
+			name, value, _ := strings.Cut(ctocEntry.Name, " ")
+			p.runtimeOptions = append(p.runtimeOptions, RuntimeOption{name, value})
+		}
+		if ctocEntry.TypeCompressedData == 'd' {
+			p.dependencies = append(p.dependencies, parseDependency(ctocEntry.Name))
+		}
+
+		p.tableOfContents = append(p.tableOfContents, ctocEntry)
+		parsedLen += int64(ctocEntry.EntrySize)
+	}
+	p.logf("[+] Found %d files in CArchive", len(p.tableOfContents))
+	return nil
+}
+
+// ReadEntry returns the contents of a CArchive entry, decompressed when the
+// entry is stored compressed.
+func (p *Archive) ReadEntry(entry CTOCEntry) ([]byte, error) {
+	data, err := p.readAt(p.overlayPosition+int64(entry.EntryPosition), int64(entry.DataSize))
+	if err != nil {
+		return nil, &EntryError{entry.Name, err}
+	}
+
+	if entry.ComressionFlag == 1 {
+		decompressed, err := zlibDecompress(data)
+		if err != nil {
+			return data, &EntryError{entry.Name, err}
+		}
+		if uint(len(decompressed)) != entry.UncompressedDataSize {
+			p.logf("[!] Warning: Decompressed size mismatch for file %s", entry.Name)
+		}
+		data = decompressed
+	}
+	return data, nil
+}
@@ -0,0 +1,236 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// WIN_CERT_TYPE_PKCS_SIGNED_DATA marks an Authenticode signature in the
+// certificate table
+const WIN_CERT_TYPE_PKCS_SIGNED_DATA = 2
+
+var (
+	ErrNoSignature = errors.New("no Authenticode signature")
+	ErrNotPKCS7    = errors.New("not PKCS#7 signed data")
+)
+
+var (
+	oidSignedData       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidSigningTime      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidCounterSignature = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 6}
+	oidRFC3161Timestamp = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 3, 3, 1}
+	oidNestedSignature  = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 4, 1}
+)
+
+// Authenticode is a decoded Authenticode signature. Nested signatures, such
+// as SHA-256 signatures added next to a SHA-1 one, are flattened into
+// Signers.
+type Authenticode struct {
+	Signers []AuthenticodeSigner
+}
+
+// AuthenticodeSigner is a signer of an Authenticode signature.
+type AuthenticodeSigner struct {
+	// Subject is empty if the signature lacks the signer's certificate
+	Subject      string
+	Issuer       string
+	SerialNumber string
+	NotBefore    time.Time
+	NotAfter     time.Time
+	// SigningTime is the time claimed by the signer, rarely present
+	SigningTime time.Time
+	// Timestamps are the times certified by countersignatures
+	Timestamps []time.Time
+}
+
+// PKCS#7 structures, from RFC 2315
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   []attribute `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes []attribute `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// tstInfo is the content of an RFC 3161 timestamp token
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint asn1.RawValue
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+}
+
+// ParseCertificateTable decodes the first Authenticode signature of a PE
+// certificate table, a list of 8 byte aligned WIN_CERTIFICATE structures.
+func ParseCertificateTable(table []byte) (*Authenticode, error) {
+	for len(table) >= 8 {
+		length := int(binary.LittleEndian.Uint32(table))
+		certType := binary.LittleEndian.Uint16(table[6:])
+		if length < 8 || length > len(table) {
+			return nil, fmt.Errorf("invalid WIN_CERTIFICATE length %d", length)
+		}
+		if certType == WIN_CERT_TYPE_PKCS_SIGNED_DATA {
+			return ParseAuthenticode(table[8:length])
+		}
+		table = table[min((length+7)&^7, len(table)):]
+	}
+	return nil, ErrNoSignature
+}
+
+// ParseAuthenticode decodes a PKCS#7 Authenticode signature.
+func ParseAuthenticode(der []byte) (*Authenticode, error) {
+	sig := &Authenticode{}
+	if err := sig.addSignedData(der, 0); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+func (sig *Authenticode) addSignedData(der []byte, depth int) error {
+	sd, err := parseSignedData(der)
+	if err != nil {
+		return err
+	}
+	var certs []*x509.Certificate
+	if len(sd.Certificates.Bytes) != 0 {
+		// Unparsable certificates only cost the signer's subject
+		certs, _ = x509.ParseCertificates(sd.Certificates.Bytes)
+	}
+
+	for _, si := range sd.SignerInfos {
+		signer := AuthenticodeSigner{Issuer: rawName(si.IssuerAndSerialNumber.Issuer.FullBytes)}
+		if si.IssuerAndSerialNumber.SerialNumber != nil {
+			signer.SerialNumber = fmt.Sprintf("%x", si.IssuerAndSerialNumber.SerialNumber)
+		}
+		for _, cert := range certs {
+			if bytes.Equal(cert.RawIssuer, si.IssuerAndSerialNumber.Issuer.FullBytes) &&
+				si.IssuerAndSerialNumber.SerialNumber != nil && cert.SerialNumber.Cmp(si.IssuerAndSerialNumber.SerialNumber) == 0 {
+				signer.Subject = cert.Subject.String()
+				signer.NotBefore, signer.NotAfter = cert.NotBefore, cert.NotAfter
+				break
+			}
+		}
+		signer.SigningTime, _ = signingTime(si.AuthenticatedAttributes)
+
+		var nested [][]byte
+		for _, attr := range si.UnauthenticatedAttributes {
+			for _, value := range attr.Values {
+				switch {
+				case attr.Type.Equal(oidCounterSignature):
+					var counter signerInfo
+					if _, err := asn1.Unmarshal(value.FullBytes, &counter); err == nil {
+						if t, ok := signingTime(counter.AuthenticatedAttributes); ok {
+							signer.Timestamps = append(signer.Timestamps, t)
+						}
+					}
+				case attr.Type.Equal(oidRFC3161Timestamp):
+					if t, err := rfc3161Time(value.FullBytes); err == nil {
+						signer.Timestamps = append(signer.Timestamps, t)
+					}
+				case attr.Type.Equal(oidNestedSignature):
+					nested = append(nested, value.FullBytes)
+				}
+			}
+		}
+		sig.Signers = append(sig.Signers, signer)
+
+		if depth < 4 {
+			for _, der := range nested {
+				sig.addSignedData(der, depth+1)
+			}
+		}
+	}
+	return nil
+}
+
+func parseSignedData(der []byte) (*signedData, error) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, err
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, ErrNotPKCS7
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, err
+	}
+	return &sd, nil
+}
+
+// signingTime returns the signingTime attribute
+func signingTime(attrs []attribute) (time.Time, bool) {
+	for _, attr := range attrs {
+		if !attr.Type.Equal(oidSigningTime) || len(attr.Values) == 0 {
+			continue
+		}
+		var t time.Time
+		if _, err := asn1.Unmarshal(attr.Values[0].FullBytes, &t); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// rfc3161Time returns the time certified by an RFC 3161 timestamp token
+func rfc3161Time(der []byte) (time.Time, error) {
+	sd, err := parseSignedData(der)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// The encapsulated content is an OCTET STRING holding the TSTInfo
+	var content []byte
+	if _, err := asn1.Unmarshal(sd.ContentInfo.Content.Bytes, &content); err != nil {
+		return time.Time{}, err
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(content, &info); err != nil {
+		return time.Time{}, err
+	}
+	return info.GenTime, nil
+}
+
+// rawName formats a DER encoded X.500 name
+func rawName(der []byte) string {
+	var rdns pkix.RDNSequence
+	if _, err := asn1.Unmarshal(der, &rdns); err != nil {
+		return ""
+	}
+	var name pkix.Name
+	name.FillFromRDNSequence(&rdns)
+	return name.String()
+}
@@ -0,0 +1,57 @@
+package pyinstaller
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAuthenticode(t *testing.T) {
+	// signed_appended.exe is signed.exe followed by 16 bytes
+	want := AuthenticodeSigner{
+		Subject:      "CN=Evil Corp Code Signing,O=Evil Corp",
+		Issuer:       "CN=Evil Corp Code Signing,O=Evil Corp",
+		SerialNumber: "1234abcd",
+		NotBefore:    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		SigningTime:  time.Date(2026, 10, 18, 8, 31, 40, 0, time.UTC),
+		Timestamps:   []time.Time{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+	for _, test := range []struct {
+		file     string
+		appended int64
+	}{
+		{"signed.exe", 0},
+		{"signed_appended.exe", 16},
+	} {
+		t.Run(test.file, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + test.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			arch := openArchive(t, data)
+			info := arch.PE()
+			if info == nil || info.Signature == nil {
+				t.Fatalf("PE() = %+v, want a signature", info)
+			}
+			if got := info.Signature.Signers; len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+				t.Errorf("signers %+v, want %+v", got, want)
+			}
+			if info.AppendedSize != test.appended {
+				t.Errorf("%d bytes appended after the signature, want %d", info.AppendedSize, test.appended)
+			}
+			if arch.archiveEnd != info.CertificateOffset {
+				t.Errorf("archive ends at %d, want the certificate table at %d", arch.archiveEnd, info.CertificateOffset)
+			}
+
+			out := NewMemSink()
+			if err := arch.ExtractFiles(out); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := out.File("data.txt"); string(got) != "pe" {
+				t.Errorf("data.txt = %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"compress/zlib"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"pyinstxtractor-go/marshal"
+)
+
+// testEntry is a CArchive entry for buildArchive
+type testEntry struct {
+	name     string
+	typ      byte
+	data     []byte
+	compress bool
+}
+
+// buildArchive lays out a CArchive the way PyInstaller's CArchiveWriter
+// does, after a fake bootloader: the entries, the table of contents and a
+// 2.1+ cookie for Python 3.11
+func buildArchive(entries []testEntry) []byte {
+	return buildArchiveFor(entries, 21, 311)
+}
+
+// buildArchiveFor is buildArchive with the cookie of pyInstVersion 20 or
+// 21, recording pythonVersion such as 27 or 311. 2.0 cookies lack the
+// library name.
+func buildArchiveFor(entries []testEntry, pyInstVersion, pythonVersion int) []byte {
+	var pkg, toc bytes.Buffer
+	for _, e := range entries {
+		raw := e.data
+		var flag byte
+		if e.compress {
+			var z bytes.Buffer
+			zw := zlib.NewWriter(&z)
+			zw.Write(e.data)
+			zw.Close()
+			raw, flag = z.Bytes(), 1
+		}
+		position := pkg.Len()
+		pkg.Write(raw)
+
+		name := append([]byte(e.name), 0)
+		for (CTOC_ENTRY_SIZE+len(name))%16 != 0 {
+			name = append(name, 0)
+		}
+		binary.Write(&toc, binary.BigEndian, []uint32{
+			uint32(CTOC_ENTRY_SIZE + len(name)), uint32(position), uint32(len(raw)), uint32(len(e.data)),
+		})
+		toc.Write([]byte{flag, e.typ})
+		toc.Write(name)
+	}
+	tocPosition := pkg.Len()
+	pkg.Write(toc.Bytes())
+
+	pkg.Write(PYINST_MAGIC[:])
+	cookieSize := PYINST21_COOKIE_SIZE
+	if pyInstVersion == 20 {
+		cookieSize = PYINST20_COOKIE_SIZE
+	}
+	binary.Write(&pkg, binary.BigEndian, []uint32{
+		uint32(pkg.Len() - len(PYINST_MAGIC) + cookieSize), uint32(tocPosition), uint32(toc.Len()), uint32(pythonVersion),
+	})
+	if pyInstVersion == 21 {
+		major, minor := pythonVersion/10, pythonVersion%10
+		if pythonVersion >= 100 {
+			major, minor = pythonVersion/100, pythonVersion%100
+		}
+		lib := make([]byte, 64)
+		copy(lib, fmt.Sprintf("libpython%d.%d.so.1.0", major, minor))
+		pkg.Write(lib)
+	}
+
+	return append([]byte("\x7fELF fake bootloader\x00\x00\x00\x00"), pkg.Bytes()...)
+}
+
+// testModule is a PYZ entry for buildPYZ, whose data is compressed
+type testModule struct {
+	name     string
+	typecode int
+	data     []byte
+}
+
+// buildPYZ lays out a PYZ archive for the given pyc magic. Its table of
+// contents is a list of (name, (typecode, position, length)) tuples, or
+// with dictTOC the dict of PyInstaller < 3.1. python2 marshals it the way
+// Python 2 does, with byte string names.
+func buildPYZ(pycMagic string, modules []testModule, dictTOC, python2 bool) []byte {
+	var b bytes.Buffer
+	b.WriteString("PYZ\x00")
+	b.WriteString(pycMagic)
+	b.Write(make([]byte, 4))
+
+	var items []marshal.Object
+	var dict []marshal.PyDictItem
+	for _, m := range modules {
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(m.data)
+		zw.Close()
+		info := marshal.NewTuple(marshal.NewInt(int32(m.typecode)), marshal.NewInt(int32(b.Len())), marshal.NewInt(int32(z.Len())))
+		b.Write(z.Bytes())
+
+		var name marshal.Object = marshal.NewString(m.name)
+		if python2 {
+			name = marshal.NewBytes([]byte(m.name))
+		}
+		items = append(items, marshal.NewTuple(name, info))
+		dict = append(dict, marshal.PyDictItem{Key: name, Value: info})
+	}
+
+	var toc marshal.Object = marshal.NewList(items...)
+	if dictTOC {
+		toc = marshal.NewDict(dict...)
+	}
+	version := 4
+	if python2 {
+		version = 2
+	}
+	data, err := marshal.Marshal(toc, version)
+	if err != nil {
+		panic(err)
+	}
+	pyz := b.Bytes()
+	binary.BigEndian.PutUint32(pyz[8:], uint32(len(pyz)))
+	return append(pyz, data...)
+}
+
+// openArchive parses data up to the table of contents
+func openArchive(t *testing.T, data []byte) *Archive {
+	t.Helper()
+	arch := NewArchive(bytes.NewReader(data), int64(len(data)), "test.bin")
+	arch.Logf = t.Logf
+	if err := arch.CheckFile(); err != nil {
+		t.Fatalf("CheckFile: %v", err)
+	}
+	if err := arch.GetCArchiveInfo(); err != nil {
+		t.Fatalf("GetCArchiveInfo: %v", err)
+	}
+	if err := arch.ParseTOC(); err != nil {
+		t.Fatalf("ParseTOC: %v", err)
+	}
+	return arch
+}
+
+// buildPE returns a PE32 file with a single section at RVA 0x1000, holding
+// section and virtualSize bytes long in memory. resourceRVA locates the
+// resource directory, unless zero. overlay is appended after the section.
+func buildPE(section []byte, virtualSize, resourceRVA uint32, overlay []byte) []byte {
+	const headersSize = 0x200
+	rawSize := (len(section) + 0x1ff) &^ 0x1ff
+
+	var b bytes.Buffer
+	dos := make([]byte, 0x40)
+	copy(dos, "MZ")
+	binary.LittleEndian.PutUint32(dos[0x3c:], 0x40)
+	b.Write(dos)
+	b.WriteString("PE\x00\x00")
+
+	oh := pe.OptionalHeader32{
+		Magic:               0x10b,
+		AddressOfEntryPoint: 0x1000,
+		ImageBase:           0x400000,
+		SectionAlignment:    0x1000,
+		FileAlignment:       0x200,
+		SizeOfImage:         0x1000 + (max(virtualSize, uint32(rawSize))+0xfff)&^0xfff,
+		SizeOfHeaders:       headersSize,
+		Subsystem:           2,
+		NumberOfRvaAndSizes: 16,
+	}
+	if resourceRVA != 0 {
+		oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE] = pe.DataDirectory{VirtualAddress: resourceRVA, Size: uint32(len(section))}
+	}
+	binary.Write(&b, binary.LittleEndian, pe.FileHeader{
+		Machine:              pe.IMAGE_FILE_MACHINE_I386,
+		NumberOfSections:     1,
+		SizeOfOptionalHeader: uint16(binary.Size(oh)),
+		Characteristics:      pe.IMAGE_FILE_EXECUTABLE_IMAGE | pe.IMAGE_FILE_32BIT_MACHINE,
+	})
+	binary.Write(&b, binary.LittleEndian, oh)
+	binary.Write(&b, binary.LittleEndian, pe.SectionHeader32{
+		Name:             [8]uint8{'.', 'r', 's', 'r', 'c'},
+		VirtualSize:      max(virtualSize, uint32(len(section))),
+		VirtualAddress:   0x1000,
+		SizeOfRawData:    uint32(rawSize),
+		PointerToRawData: headersSize,
+		Characteristics:  pe.IMAGE_SCN_CNT_INITIALIZED_DATA | pe.IMAGE_SCN_MEM_READ,
+	})
+	b.Write(make([]byte, headersSize-b.Len()))
+	b.Write(section)
+	b.Write(make([]byte, rawSize-len(section)))
+	b.Write(overlay)
+	return b.Bytes()
+}
+
+// buildFatMachO returns a universal binary of x86_64 and arm64 slices, each
+// a bare 64-bit Mach-O header followed by its payload
+func buildFatMachO(payloads [][]byte) []byte {
+	cpus := []uint32{uint32(macho.CpuAmd64), uint32(macho.CpuArm64)}
+	var slices [][]byte
+	for i, payload := range payloads {
+		var b bytes.Buffer
+		binary.Write(&b, binary.LittleEndian, macho.FileHeader{Magic: macho.Magic64, Cpu: macho.Cpu(cpus[i]), Type: macho.TypeExec})
+		b.Write(make([]byte, 4)) // reserved field of mach_header_64
+		b.Write(payload)
+		slices = append(slices, b.Bytes())
+	}
+
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, []uint32{macho.MagicFat, uint32(len(slices))})
+	offset := 0x1000
+	for i, slice := range slices {
+		binary.Write(&b, binary.BigEndian, []uint32{cpus[i], 0, uint32(offset), uint32(len(slice)), 12})
+		offset += (len(slice) + 0xfff) &^ 0xfff
+	}
+	for _, slice := range slices {
+		b.Write(make([]byte, (b.Len()+0xfff)&^0xfff-b.Len()))
+		b.Write(slice)
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,98 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"pyinstxtractor-go/marshal"
+)
+
+var ErrNotCodeObject = errors.New("not a code object")
+
+// pycHeaderSize returns the size of the pyc header written by the given
+// Python version.
+func pycHeaderSize(major, minor int) int {
+	if major >= 3 && minor >= 7 {
+		// PEP 552 -- Deterministic pycs
+		return 16
+	}
+	if major >= 3 && minor >= 3 {
+		return 12
+	}
+	return 8
+}
+
+// pycMagicRanges maps the magic numbers used by development and final
+// releases, from Lib/importlib/_bootstrap_external.py, to Python versions
+var pycMagicRanges = []struct {
+	first, last  uint16
+	major, minor int
+}{
+	{62171, 62211, 2, 7},
+	{3000, 3131, 3, 0},
+	{3141, 3151, 3, 1},
+	{3160, 3180, 3, 2},
+	{3190, 3230, 3, 3},
+	{3250, 3310, 3, 4},
+	{3320, 3351, 3, 5},
+	{3360, 3379, 3, 6},
+	{3390, 3394, 3, 7},
+	{3400, 3413, 3, 8},
+	{3420, 3425, 3, 9},
+	{3430, 3439, 3, 10},
+	{3450, 3495, 3, 11},
+	{3500, 3531, 3, 12},
+	{3550, 3571, 3, 13},
+	{3600, 3627, 3, 14},
+}
+
+// pycVersion returns the Python version which wrote a pyc header
+func pycVersion(header []byte) (major, minor int, ok bool) {
+	if len(header) < 4 || header[2] != '\r' || header[3] != '\n' {
+		return 0, 0, false
+	}
+	magic := binary.LittleEndian.Uint16(header)
+	for _, r := range pycMagicRanges {
+		if magic >= r.first && magic <= r.last {
+			return r.major, r.minor, true
+		}
+	}
+	return 0, 0, false
+}
+
+// UnmarshalCode decodes a marshalled code object without pyc header, as
+// stored in the CArchive and PYZ archives, using the code object layout of
+// the given Python version.
+func UnmarshalCode(data []byte, major, minor int) (*marshal.PyCodeObject, error) {
+	obj, err := marshal.NewUnmarshalerForVersion(bytes.NewReader(data), major, minor).Unmarshal()
+	if err != nil {
+		return nil, err
+	}
+	code, ok := marshal.AsCode(obj)
+	if !ok {
+		return nil, fmt.Errorf("%w: got %T", ErrNotCodeObject, obj)
+	}
+	return code, nil
+}
+
+// UnmarshalPyc decodes the code object of a pyc file written by the given
+// Python version.
+func UnmarshalPyc(data []byte, major, minor int) (*marshal.PyCodeObject, error) {
+	headerSize := pycHeaderSize(major, minor)
+	if len(data) < headerSize {
+		return nil, ErrTooShort
+	}
+	return UnmarshalCode(data[headerSize:], major, minor)
+}
+
+// UnmarshalCode decodes a headerless code object belonging to this archive.
+func (p *Archive) UnmarshalCode(data []byte) (*marshal.PyCodeObject, error) {
+	return UnmarshalCode(data, p.pythonMajorVersion, p.pythonMinorVersion)
+}
+
+// UnmarshalPyc decodes a pyc file extracted from this archive.
+func (p *Archive) UnmarshalPyc(data []byte) (*marshal.PyCodeObject, error) {
+	return UnmarshalPyc(data, p.pythonMajorVersion, p.pythonMinorVersion)
+}
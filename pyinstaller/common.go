@@ -1,4 +1,4 @@
-package main
+package pyinstaller
 
 import (
 	"bytes"
@@ -10,6 +10,16 @@ import (
 const (
 	PYINST20_COOKIE_SIZE = 24      // For pyinstaller 2.0
 	PYINST21_COOKIE_SIZE = 24 + 64 // For pyinstaller 2.1+
+	CTOC_ENTRY_SIZE      = 18      // Followed by the entry name
+)
+
+// Typecodes of PYZ entries. Before PyInstaller 6 the field was an ispkg flag,
+// which maps onto the first two.
+const (
+	PYZ_ITEM_MODULE = 0
+	PYZ_ITEM_PKG    = 1
+	PYZ_ITEM_DATA   = 2
+	PYZ_ITEM_NSPKG  = 3 // PEP 420 namespace package
 )
 
 var PYINST_MAGIC [8]byte = [8]byte{'M', 'E', 'I', 014, 013, 012, 013, 016} // Magic number which identifies pyinstaller
@@ -0,0 +1,189 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+
+	"pyinstxtractor-go/disasm"
+	"pyinstxtractor-go/marshal"
+)
+
+// CRYPT_BLOCK_SIZE is the size of the AES key and of the IV stored in front
+// of every encrypted PYZ entry
+const CRYPT_BLOCK_SIZE = 16
+
+// CRYPTO_KEY_MODULE is the module holding the key of encrypted PYZ archives
+const CRYPTO_KEY_MODULE = "pyimod00_crypto_key"
+
+var ErrNoKey = errors.New("no decryption key")
+
+// pyzCipher is the scheme used to encrypt PYZ entries
+type pyzCipher int
+
+const (
+	cipherUnknown pyzCipher = iota
+	cipherCTR               // tinyaes AES-CTR, PyInstaller 4.0 - 5.x
+	cipherCFB               // pycrypto AES-CFB, PyInstaller < 4.0
+)
+
+func (c pyzCipher) String() string {
+	switch c {
+	case cipherCTR:
+		return "AES-CTR"
+	case cipherCFB:
+		return "AES-CFB"
+	}
+	return "unknown"
+}
+
+// normalizeKey pads or truncates the key to CRYPT_BLOCK_SIZE the way the
+// bootloader does, left padding with '0' like str.zfill
+func normalizeKey(key []byte) []byte {
+	if len(key) >= CRYPT_BLOCK_SIZE {
+		return key[:CRYPT_BLOCK_SIZE]
+	}
+	return append(bytes.Repeat([]byte("0"), CRYPT_BLOCK_SIZE-len(key)), key...)
+}
+
+// decrypt decrypts an encrypted PYZ entry, which starts with the IV
+func (c pyzCipher) decrypt(key, data []byte) ([]byte, error) {
+	if len(data) < CRYPT_BLOCK_SIZE {
+		return nil, ErrTooShort
+	}
+	block, err := aes.NewCipher(normalizeKey(key))
+	if err != nil {
+		return nil, err
+	}
+	iv, data := data[:CRYPT_BLOCK_SIZE], data[CRYPT_BLOCK_SIZE:]
+	out := make([]byte, len(data))
+
+	switch c {
+	case cipherCTR:
+		cipher.NewCTR(block, iv).XORKeyStream(out, data)
+	case cipherCFB:
+		// pycrypto defaults to 8 bit segments, which crypto/cipher lacks
+		register := append([]byte(nil), iv...)
+		stream := make([]byte, CRYPT_BLOCK_SIZE)
+		for i, b := range data {
+			block.Encrypt(stream, register)
+			out[i] = b ^ stream[0]
+			copy(register, register[1:])
+			register[CRYPT_BLOCK_SIZE-1] = b
+		}
+	default:
+		return nil, fmt.Errorf("unknown cipher %d", c)
+	}
+	return out, nil
+}
+
+// decryptPYZEntry decrypts and decompresses an encrypted PYZ entry. The
+// scheme is detected on the first entry by checking which one yields valid
+// zlib data.
+func (p *Archive) decryptPYZEntry(data []byte) ([]byte, error) {
+	key := p.cryptoKey()
+	if key == nil {
+		return nil, ErrNoKey
+	}
+
+	ciphers := []pyzCipher{cipherCTR, cipherCFB}
+	if p.pyzCipher != cipherUnknown {
+		ciphers = []pyzCipher{p.pyzCipher}
+	}
+	var lastErr error
+	for _, c := range ciphers {
+		decrypted, err := c.decrypt(key, data)
+		if err != nil {
+			return nil, err
+		}
+		decompressed, err := zlibDecompress(decrypted)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if p.pyzCipher == cipherUnknown {
+			p.pyzCipher = c
+			p.logf("[+] PYZ archive is encrypted with %s", c)
+		}
+		return decompressed, nil
+	}
+	return nil, lastErr
+}
+
+// cryptoKey returns the Key override, or else the key recovered from the
+// CRYPTO_KEY_MODULE entry of the CArchive. It returns nil if neither exists.
+func (p *Archive) cryptoKey() []byte {
+	if p.Key != nil {
+		return p.Key
+	}
+	if p.keySearched {
+		return p.recoveredKey
+	}
+	p.keySearched = true
+
+	for _, entry := range p.tableOfContents {
+		if entry.Name != CRYPTO_KEY_MODULE {
+			continue
+		}
+		data, err := p.ReadEntry(entry)
+		if err != nil {
+			p.logf("[!] Error: Failed to read %s: %v", entry.Name, err)
+			return nil
+		}
+
+		var code *marshal.PyCodeObject
+		if len(data) >= 4 && data[2] == '\r' && data[3] == '\n' {
+			code, err = p.UnmarshalPyc(data)
+		} else {
+			code, err = p.UnmarshalCode(data)
+		}
+		if err != nil {
+			p.logf("[!] Error: Failed to decode %s: %v", entry.Name, err)
+			return nil
+		}
+
+		key, ok := keyFromModule(code)
+		if !ok {
+			p.logf("[!] Error: Couldn't find the key in %s", entry.Name)
+			return nil
+		}
+		p.logf("[+] Found PYZ decryption key: %s", key)
+		p.recoveredKey = []byte(key)
+		return p.recoveredKey
+	}
+	return nil
+}
+
+// keyFromModule returns the string assigned to key by the code of the
+// CRYPTO_KEY_MODULE module, falling back to its first string constant. The
+// constants are looked up by index rather than formatted, as the module comes
+// from the archive.
+func keyFromModule(code *marshal.PyCodeObject) (string, bool) {
+	consts := code.GetConsts()
+	names := code.GetNames()
+	if instructions, err := disasm.RawInstructions(code); err == nil {
+		var last marshal.Object
+		for _, inst := range instructions {
+			switch inst.Name {
+			case "LOAD_CONST":
+				if inst.Arg < len(consts) {
+					last = consts[inst.Arg]
+				}
+			case "STORE_NAME", "STORE_GLOBAL":
+				if inst.Arg < len(names) && names[inst.Arg] == "key" {
+					if key, ok := marshal.AsString(last); ok {
+						return key, true
+					}
+				}
+			}
+		}
+	}
+	for _, obj := range consts {
+		if key, ok := marshal.AsString(obj); ok {
+			return key, true
+		}
+	}
+	return "", false
+}
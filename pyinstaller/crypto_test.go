@@ -0,0 +1,95 @@
+package pyinstaller
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCryptoKeyCyclicConstant(t *testing.T) {
+	// The key module also loads a constant list holding itself
+	data, err := os.ReadFile("testdata/cyclic_key.pyc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	arch := openArchive(t, buildArchive([]testEntry{{CRYPTO_KEY_MODULE, 'm', data, true}}))
+	if key := string(arch.cryptoKey()); key != "0123456789abcdef" {
+		t.Errorf("key = %q", key)
+	}
+}
+
+func TestDecryptPYZ(t *testing.T) {
+	keyModule, err := os.ReadFile("testdata/cyclic_key.pyc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fixtures were encrypted with the key of cyclic_key.pyc
+	tests := []struct {
+		name      string
+		pyz       string
+		keyModule bool
+		key       string
+		decrypted bool
+	}{
+		{"AES-CTR", "encrypted_ctr.pyz", true, "", true},
+		{"AES-CFB", "encrypted_cfb.pyz", true, "", true},
+		{"key override", "encrypted_ctr.pyz", false, "0123456789abcdef", true},
+		{"wrong key", "encrypted_cfb.pyz", true, "not the key", false},
+		{"missing key", "encrypted_ctr.pyz", false, "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pyz, err := os.ReadFile("testdata/" + test.pyz)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries := []testEntry{{"PYZ.pyz", 'z', pyz, false}}
+			if test.keyModule {
+				entries = append(entries, testEntry{CRYPTO_KEY_MODULE, 'm', keyModule, true})
+			}
+			arch := openArchive(t, buildArchive(entries))
+			if test.key != "" {
+				arch.Key = []byte(test.key)
+			}
+			var failures []string
+			arch.Logf = func(format string, args ...any) {
+				if msg := fmt.Sprintf(format, args...); strings.HasPrefix(msg, "[!] Error") {
+					failures = append(failures, msg)
+				}
+			}
+			out := NewMemSink()
+			if err := arch.ExtractFiles(out); err != nil {
+				t.Fatal(err)
+			}
+
+			modules := []struct {
+				name    string
+				assigns string
+			}{
+				{"PYZ.pyz_extracted/foo.pyc", "X"},
+				{"PYZ.pyz_extracted/pkg/__init__.pyc", "Y"},
+			}
+			for _, module := range modules {
+				pyc, ok := out.File(module.name)
+				if !test.decrypted {
+					if _, encrypted := out.File(module.name + ".encrypted"); ok || !encrypted {
+						t.Errorf("%s was written decrypted", module.name)
+					}
+					continue
+				}
+				code, err := arch.UnmarshalPyc(pyc)
+				if !ok || err != nil {
+					t.Errorf("%s: %v", module.name, err)
+					continue
+				}
+				if names := code.GetNames(); len(names) != 1 || names[0] != module.assigns {
+					t.Errorf("%s assigns %q", module.name, names)
+				}
+			}
+			if test.decrypted != (len(failures) == 0) {
+				t.Errorf("errors %q", failures)
+			}
+		})
+	}
+}
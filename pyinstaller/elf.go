@@ -0,0 +1,31 @@
+package pyinstaller
+
+import (
+	"debug/elf"
+	"io"
+)
+
+// PYDATA_SECTION is the ELF section holding the archive in Linux
+// bootloaders of PyInstaller 4.x and later
+const PYDATA_SECTION = "pydata"
+
+// elfSection returns the file offset and size of the named section, if the
+// input is an ELF file which has it
+func (p *Archive) elfSection(name string) (offset, size int64, ok bool) {
+	f, err := elf.NewFile(io.NewSectionReader(p.r, 0, p.fileSize))
+	if err != nil {
+		return 0, 0, false
+	}
+	defer f.Close()
+
+	section := f.Section(name)
+	if section == nil || section.Type == elf.SHT_NOBITS {
+		return 0, 0, false
+	}
+	offset, size = int64(section.Offset), int64(section.Size)
+	if offset < 0 || size < 0 || offset+size > p.fileSize {
+		p.logf("[!] Warning: ELF section %s lies outside the file", name)
+		return 0, 0, false
+	}
+	return offset, size, true
+}
@@ -0,0 +1,40 @@
+package pyinstaller
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestELFSection(t *testing.T) {
+	// pydata.elf holds an archive in its pydata section, and another one
+	// appended after the section headers and some padding
+	data, err := os.ReadFile("testdata/pydata.elf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	arch := openArchive(t, data)
+
+	offset, size, ok := arch.elfSection(PYDATA_SECTION)
+	if !ok || offset != 64 || size != 136 {
+		t.Fatalf("pydata section at %d, %d bytes, %v", offset, size, ok)
+	}
+	if arch.cookiePosition != offset+size-PYINST21_COOKIE_SIZE {
+		t.Errorf("cookie at %d, want the end of the section", arch.cookiePosition)
+	}
+	// The end of file scan would pick the appended archive
+	if last, _, _ := arch.locateCookie(0, arch.fileSize); last != arch.fileSize-PYINST21_COOKIE_SIZE {
+		t.Errorf("end of file scan found the cookie at %d", last)
+	}
+	if position, trailing := arch.TrailingData(); position != offset+size || trailing != 0 {
+		t.Errorf("trailing data at %d, %d bytes", position, trailing)
+	}
+
+	out := NewMemSink()
+	if err := arch.ExtractFiles(out); err != nil {
+		t.Fatal(err)
+	}
+	if names := out.Names(); !reflect.DeepEqual(names, []string{"main.pyc"}) {
+		t.Errorf("extracted %q", names)
+	}
+}
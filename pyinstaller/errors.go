@@ -0,0 +1,31 @@
+package pyinstaller
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrTooShort       = errors.New("file is too short or truncated")
+	ErrMissingCookie  = errors.New("missing cookie, unsupported pyinstaller version or not a pyinstaller archive")
+	ErrNotPyInstaller = errors.New("the file is not a pyinstaller archive")
+	ErrInvalidTOC     = errors.New("invalid table of contents")
+	ErrNotParsed      = errors.New("archive has not been parsed yet")
+	ErrInvalidSplash  = errors.New("invalid splash screen resources")
+	ErrUnsafePath     = errors.New("path leaves the extraction directory or goes through a symlink")
+)
+
+// EntryError records a failure to process a single entry of the CArchive or
+// the PYZ archive.
+type EntryError struct {
+	Name string
+	Err  error
+}
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Name, e.Err)
+}
+
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
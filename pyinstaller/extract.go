@@ -0,0 +1,386 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"pyinstxtractor-go/disasm"
+	"pyinstxtractor-go/marshal"
+)
+
+func (p *Archive) ensureUnique(fileName, ext string) string {
+	exists := p.out.Exists(fileName + ext)
+	for _, b := range p.barePycsList {
+		if b.filepath == fileName+ext {
+			exists = true
+			break
+		}
+	}
+	if exists {
+		newName := fileName + "_" + randomString()
+		p.logf("[!] Warning: %s already exists, saving as %s", fileName+ext, newName+ext)
+		return newName
+	}
+	return fileName
+}
+
+// ExtractFiles extracts every entry of the CArchive into out, unpacking PYZ
+// archives along the way.
+func (p *Archive) ExtractFiles(out Sink) error {
+	if p.tableOfContents == nil {
+		return ErrNotParsed
+	}
+	p.logf("[+] Beginning extraction...please standby")
+	p.out = out
+	p.symlinks = nil
+	p.links = make(map[string]string)
+	p.unzippedSize = 0
+	p.splash = nil
+	p.extractedFiles = nil
+	p.nested = nil
+	defer func() { p.out = nil }()
+
+	for _, entry := range p.tableOfContents {
+		data, err := p.ReadEntry(entry)
+		if err != nil {
+			if data == nil {
+				p.logf("[!] Error: Failed to read %s in CArchive: %v", entry.Name, err)
+				continue
+			}
+			p.logf("[!] Error: Failed to decompress %s in CArchive, extracting as-is", entry.Name)
+			p.writeRawData(sanitizePath(entry.Name), data)
+			continue
+		}
+
+		if entry.TypeCompressedData == 'd' || entry.TypeCompressedData == 'o' {
+			// d -> ARCHIVE_ITEM_DEPENDENCY
+			// o -> ARCHIVE_ITEM_RUNTIME_OPTION
+			// These aren't files. Options are listed by RuntimeOptions and
+			// dependencies are pulled from sibling executables by
+			// extractDependencies
+			continue
+		}
+
+		entryPath := sanitizePath(entry.Name)
+		switch entry.TypeCompressedData {
+		case 's':
+			// s -> ARCHIVE_ITEM_PYSOURCE
+			// Entry point are expected to be python scripts
+			p.logf("[+] Possible entry point: %s.pyc", entry.Name)
+			entryPath = p.ensureUnique(entryPath, ".pyc")
+			p.writeBarePyc(entryPath+".pyc", data)
+		case 'M', 'm':
+			// M -> ARCHIVE_ITEM_PYPACKAGE
+			// m -> ARCHIVE_ITEM_PYMODULE
+			// packages and modules are pyc files with their header intact
+
+			// From PyInstaller 5.3 and above pyc headers are no longer stored
+			// https://github.com/pyinstaller/pyinstaller/commit/a97fdf
+
+			entryPath = p.ensureUnique(entryPath, ".pyc")
+
+			if len(data) >= 4 && data[2] == '\r' && data[3] == '\n' {
+				// < pyinstaller 5.3
+				if !p.gotPycMagic {
+					copy(p.pycMagic[:], data[0:4])
+					p.gotPycMagic = true
+				}
+				p.writeRawData(entryPath+".pyc", data)
+				p.writeDisassembly(entryPath+".pyc", data)
+			} else {
+				// >= pyinstaller 5.3
+				p.writeBarePyc(entryPath+".pyc", data)
+			}
+		case 'n':
+			// n -> ARCHIVE_ITEM_SYMLINK
+			// The data is the link target, relative to the link's directory
+			entryPath = p.ensureUnique(entryPath, "")
+			p.writeSymlink(entryPath, string(data))
+		default:
+			entryPath = p.ensureUnique(entryPath, "")
+			p.writeRawData(entryPath, data)
+
+			// z -> ARCHIVE_ITEM_PYZ
+			// Z -> ARCHIVE_ITEM_ZIPFILE, which older releases use for PYZ
+			// archives as well
+			isZip := bytes.HasPrefix(data, []byte("PK\x03\x04"))
+			switch {
+			case entry.TypeCompressedData == 'Z' && isZip, isEmbeddedZip(entryPath) && isZip:
+				if err := p.extractZip(entryPath, data, 0); err != nil {
+					p.logf("[!] Failed to extract zip %s: %v", entry.Name, err)
+				}
+			case entry.TypeCompressedData == 'z' || entry.TypeCompressedData == 'Z':
+				if err := p.extractPYZ(entryPath, data); err != nil {
+					p.logf("[!] Failed to extract pyz %s: %v", entry.Name, err)
+				}
+			}
+			if entry.TypeCompressedData == 'l' {
+				// l -> ARCHIVE_ITEM_SPLASH
+				if err := p.extractSplash(entryPath, data); err != nil {
+					p.logf("[!] Failed to extract splash screen %s: %v", entry.Name, err)
+				}
+			}
+		}
+	}
+	p.writePEResources()
+	p.extractDependencies()
+	p.fixBarePycs()
+	if p.OnedirRoot != "" {
+		p.copyOnedir()
+	}
+	p.extractNested()
+	p.writeSymlinksManifest()
+	if p.OnedirRoot != "" {
+		p.writeOnedirManifest()
+	}
+	return nil
+}
+
+// writeBarePyc writes a pyc without header, deferring it to fixBarePycs when
+// the pyc magic isn't known yet.
+func (p *Archive) writeBarePyc(path string, data []byte) {
+	if !p.gotPycMagic {
+		// if we don't have the pyc header yet, fix them in a later pass
+		p.barePycsList = append(p.barePycsList, &barePyc{path, data})
+		return
+	}
+	p.writePyc(path, data)
+}
+
+func (p *Archive) fixBarePycs() {
+	if len(p.barePycsList) != 0 && !p.gotPycMagic {
+		p.logf("[!] Warning: Could not determine the pyc magic, pyc headers will be zeroed")
+	}
+	for _, pycFile := range p.barePycsList {
+		p.writePyc(pycFile.filepath, pycFile.contents)
+	}
+	p.barePycsList = nil
+}
+
+func (p *Archive) extractPYZ(pyzPath string, pyzData []byte) error {
+	dirName := pyzPath + "_extracted"
+
+	f := bytes.NewReader(pyzData)
+	var pyzMagic []byte = make([]byte, 4)
+	f.Read(pyzMagic)
+	if !bytes.Equal(pyzMagic, []byte("PYZ\x00")) {
+		p.logf("[!] Magic header in PYZ archive doesn't match")
+	}
+
+	var pyzPycMagic []byte = make([]byte, 4)
+	f.Read(pyzPycMagic)
+
+	if !p.gotPycMagic {
+		copy(p.pycMagic[:], pyzPycMagic)
+		p.gotPycMagic = true
+	} else if !bytes.Equal(p.pycMagic[:], pyzPycMagic) {
+		copy(p.pycMagic[:], pyzPycMagic)
+		p.gotPycMagic = true
+		p.logf("[!] Warning: pyc magic of files inside PYZ archive are different from those in CArchive")
+	}
+
+	// Older ZlibArchives follow the TOC position with the compression level
+	// and an encryption flag, which aren't needed here
+	var pyzTocPositionBytes []byte = make([]byte, 4)
+	f.Read(pyzTocPositionBytes)
+	pyzTocPosition := binary.BigEndian.Uint32(pyzTocPositionBytes)
+	if _, err := f.Seek(int64(pyzTocPosition), io.SeekStart); err != nil {
+		return err
+	}
+
+	su := marshal.NewUnmarshalerForVersion(f, p.pythonMajorVersion, p.pythonMinorVersion)
+	obj, err := su.Unmarshal()
+	if err != nil {
+		return fmt.Errorf("unmarshalling PYZ table of contents failed: %w", err)
+	}
+
+	tocEntries, err := pyzTOCEntries(obj)
+	if err != nil {
+		return err
+	}
+	p.logf("[+] Found %d files in PYZArchive", len(tocEntries))
+
+	for i, tocEntry := range tocEntries {
+		name, typecode, position, length, err := parsePYZEntry(tocEntry.Key, tocEntry.Value)
+		if err != nil {
+			p.logf("[!] Error: Invalid PYZ table of contents entry %d: %v", i, err)
+			continue
+		}
+
+		// Prevent writing outside dirName
+		filename := strings.ReplaceAll(name, "..", "__")
+		filename = strings.ReplaceAll(filename, ".", "/")
+		filename = sanitizePath(filename)
+
+		var filenamepath string
+		switch typecode {
+		case PYZ_ITEM_NSPKG:
+			// Namespace packages have no code, only a directory
+			p.makeDir(path.Join(dirName, filename))
+			continue
+		case PYZ_ITEM_PKG:
+			filenamepath = path.Join(dirName, filename, "__init__.pyc")
+		case PYZ_ITEM_DATA:
+			// Data files are named by their path rather than a module name
+			filenamepath = path.Join(dirName, sanitizePath(name))
+		case PYZ_ITEM_MODULE:
+			filenamepath = path.Join(dirName, filename+".pyc")
+		default:
+			p.logf("[!] Warning: Unknown typecode %d of %s in PYZArchive, extracting as a module", typecode, name)
+			filenamepath = path.Join(dirName, filename+".pyc")
+		}
+
+		if position < 0 || length < 0 || position+length > len(pyzData) {
+			p.logf("[!] Error: Entry %s lies outside the PYZArchive, skipping", name)
+			continue
+		}
+		compressedData := pyzData[position : position+length]
+
+		decompressedData, err := zlibDecompress(compressedData)
+		if err != nil {
+			decompressedData, err = p.decryptPYZEntry(compressedData)
+		}
+		switch {
+		case err != nil:
+			p.logf("[!] Error: Failed to decompress %s in PYZArchive, likely encrypted. Extracting as is", filenamepath)
+			p.writeRawData(filenamepath+".encrypted", compressedData)
+		case typecode == PYZ_ITEM_DATA:
+			p.writeRawData(filenamepath, decompressedData)
+		default:
+			p.writePyc(filenamepath, decompressedData)
+		}
+	}
+	return nil
+}
+
+// pyzTOCEntries returns the (name, info) pairs of a PYZ table of contents.
+// PyInstaller 3.1 and later marshal a list of (name, info) tuples, older
+// releases a dict mapping names to info. Malformed list items are returned
+// with a nil name for parsePYZEntry to report.
+func pyzTOCEntries(obj marshal.Object) ([]marshal.PyDictItem, error) {
+	if items, ok := marshal.AsDict(obj); ok {
+		return items, nil
+	}
+	listItems, ok := marshal.AsSequence(obj)
+	if !ok {
+		return nil, fmt.Errorf("%w: PYZ table of contents is a %T", ErrInvalidTOC, obj)
+	}
+	entries := make([]marshal.PyDictItem, len(listItems))
+	for i, item := range listItems {
+		if pair, ok := marshal.AsSequence(item); ok && len(pair) == 2 {
+			entries[i] = marshal.PyDictItem{Key: pair[0], Value: pair[1]}
+		}
+	}
+	return entries, nil
+}
+
+// parsePYZEntry unpacks the name and (typecode, position, length) info of a
+// PYZ table of contents entry
+func parsePYZEntry(nameObj, infoObj marshal.Object) (name string, typecode, position, length int, err error) {
+	if nameObj == nil {
+		return "", 0, 0, 0, fmt.Errorf("%w: expected a (name, info) tuple", ErrInvalidTOC)
+	}
+	var ok bool
+	if name, ok = marshal.AsString(nameObj); !ok {
+		return "", 0, 0, 0, fmt.Errorf("%w: entry name is a %T", ErrInvalidTOC, nameObj)
+	}
+
+	info, ok := marshal.AsSequence(infoObj)
+	if !ok || len(info) != 3 {
+		return name, 0, 0, 0, fmt.Errorf("%w: %s: expected a (typecode, position, length) tuple", ErrInvalidTOC, name)
+	}
+	typecode, ok1 := marshal.AsInt(info[0])
+	position, ok2 := marshal.AsInt(info[1])
+	length, ok3 := marshal.AsInt(info[2])
+	if !ok1 || !ok2 || !ok3 {
+		return name, 0, 0, 0, fmt.Errorf("%w: %s: non-integer entry info", ErrInvalidTOC, name)
+	}
+	return name, typecode, position, length, nil
+}
+
+// pycHeader returns the header to prepend to headerless code objects.
+// The fields following the magic are zeroed: the bitfield, timestamp and
+// source size, or hash, depending on the Python version.
+func (p *Archive) pycHeader() []byte {
+	header := make([]byte, pycHeaderSize(p.pythonMajorVersion, p.pythonMinorVersion))
+	copy(header, p.pycMagic[:])
+	return header
+}
+
+func (p *Archive) writePyc(path string, data []byte) {
+	pyc := append(p.pycHeader(), data...)
+	p.writeRawData(path, pyc)
+	p.writeDisassembly(path, pyc)
+}
+
+// writeDisassembly writes the listing of the pyc at pycPath next to it, when
+// enabled
+func (p *Archive) writeDisassembly(pycPath string, pyc []byte) {
+	if !p.Disassemble {
+		return
+	}
+	code, err := p.UnmarshalPyc(pyc)
+	if err != nil {
+		p.logf("[!] Error: Failed to disassemble %s: %v", pycPath, err)
+		return
+	}
+	var listing bytes.Buffer
+	if err := disasm.Disassemble(&listing, code); err != nil {
+		p.logf("[!] Error: Failed to disassemble %s: %v", pycPath, err)
+		return
+	}
+	p.writeRawData(strings.TrimSuffix(pycPath, ".pyc")+".dis", listing.Bytes())
+}
+
+// makeDir creates an empty directory, if the sink can hold one. Otherwise it
+// only exists through the files written below it.
+func (p *Archive) makeDir(name string) {
+	d, ok := p.out.(DirMaker)
+	if !ok {
+		return
+	}
+	if p.throughSymlink(name) {
+		p.logf("[!] Error: %s lies below a symlink, not creating it", name)
+		return
+	}
+	if err := d.MkdirAll(name); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		p.logf("[!] Failed to create directory %s", name)
+	}
+}
+
+func (p *Archive) writeRawData(path string, data []byte) {
+	if p.throughSymlink(path) {
+		p.logf("[!] Error: %s lies below a symlink, not writing it", path)
+		return
+	}
+	if err := p.out.WriteFile(path, data); err != nil {
+		p.logf("[!] Failed to write file %s", path)
+		return
+	}
+	p.recordFile(path)
+	p.checkNested(path, data)
+}
+
+// recordFile adds a written file to ExtractedFiles
+func (p *Archive) recordFile(path string) {
+	source := p.fileSource
+	if source == "" {
+		source = filepath.Base(p.Name)
+	}
+	p.extractedFiles = append(p.extractedFiles, ExtractedFile{path, source})
+}
+
+// sanitizePath turns an archive member name into a relative, slash separated
+// path that stays within the extraction directory.
+func sanitizePath(name string) string {
+	name = strings.Trim(name, "\x00")
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = strings.ReplaceAll(name, "..", "__")
+	return strings.TrimLeft(name, "/")
+}
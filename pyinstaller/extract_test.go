@@ -0,0 +1,91 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+
+	"pyinstxtractor-go/marshal"
+)
+
+func TestExtractFiles(t *testing.T) {
+	// basic.bin holds scripts, modules, a PYZ, options, data and a binary
+	data, err := os.ReadFile("testdata/basic.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	arch := openArchive(t, data)
+	out := NewMemSink()
+	if err := arch.ExtractFiles(out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"PYZ.pyz",
+		"PYZ.pyz_extracted/foo.pyc",
+		"PYZ.pyz_extracted/pkg/__init__.pyc",
+		"PYZ.pyz_extracted/pkg/sub.pyc",
+		"data/readme.txt",
+		"libfoo.so",
+		"main.pyc",
+		"pyiboot01_bootstrap.pyc",
+		"pyimod01_archive.pyc",
+	}
+	if names := out.Names(); !reflect.DeepEqual(names, want) {
+		t.Errorf("extracted %q, want %q", names, want)
+	}
+	if got, _ := out.File("data/readme.txt"); string(got) != "hello data" {
+		t.Errorf("data/readme.txt = %q", got)
+	}
+	options := []RuntimeOption{{"W", "ignore"}, {"pyi-contents-directory", "_internal"}}
+	if got := arch.RuntimeOptions(); !reflect.DeepEqual(got, options) {
+		t.Errorf("runtime options %+v, want %+v", got, options)
+	}
+
+	if pyc, _ := out.File("main.pyc"); !bytes.HasPrefix(pyc, []byte("\xa7\r\r\n")) {
+		t.Errorf("main.pyc has the header %q", pyc[:min(len(pyc), 16)])
+	}
+	pyc, _ := out.File("PYZ.pyz_extracted/foo.pyc")
+	code, err := arch.UnmarshalPyc(pyc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, _ := new(big.Int).SetString("12345678901234567890", 10)
+	if got, ok := marshal.AsBigInt(code.GetConsts()[0]); !ok || got.Cmp(x) != 0 {
+		t.Errorf("foo.X = %v", got)
+	}
+}
+
+// FuzzExtract runs arbitrary input through the extraction, with the inputs
+// of earlier crashes, hangs and escapes as seeds
+func FuzzExtract(f *testing.F) {
+	archive := buildArchive([]testEntry{{"main", 's', []byte("code"), true}})
+	f.Add(archive)
+	f.Add(buildArchive(escapeEntries))
+	f.Add(buildArchive([]testEntry{{"data.zip", 'Z', zipBombs(f), false}}))
+	f.Add(buildPE(make([]byte, 0x200), 0x1000, 0x1800, archive))
+	f.Add(buildPE(sharedResourceDirs(3000), 0, 0x1000, archive))
+	f.Add(append(make([]byte, 0x200), archive...))
+	for _, name := range []string{"basic.bin", "cyclic_key.pyc", "thin.macho", "signed.macho", "fat.macho", "pydata.elf", "signed_appended.exe"} {
+		data, err := os.ReadFile("testdata/" + name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		if name == "cyclic_key.pyc" {
+			data = buildArchive([]testEntry{{CRYPTO_KEY_MODULE, 'm', data, true}, {"PYZ.pyz", 'z', nil, false}})
+		}
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		arch := NewArchive(bytes.NewReader(data), int64(len(data)), "fuzz.bin")
+		arch.Logf = func(string, ...any) {}
+		arch.Disassemble = true
+		if arch.CheckFile() != nil || arch.GetCArchiveInfo() != nil || arch.ParseTOC() != nil {
+			return
+		}
+		arch.ExtractFiles(NewMemSink())
+	})
+}
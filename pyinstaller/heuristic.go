@@ -0,0 +1,155 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// CTOC_ENTRY_TYPECODES are the typecodes a CArchive entry may carry
+const CTOC_ENTRY_TYPECODES = "bsmMzZxodnl"
+
+// Limits of the heuristic scan, which tries every offset of the file
+const (
+	maxCookieCandidates = 256  // candidates whose table of contents is checked
+	maxTOCCheckSize     = 4096 // bytes of each table of contents checked
+)
+
+// locateCookie returns the position of the cookie between start and end, or
+// -1, along with its PyInstaller version if the structure gave it away. With
+// Heuristic set, a region lacking PYINST_MAGIC is scanned for cookie-shaped
+// structures.
+func (p *Archive) locateCookie(start, end int64) (int64, int64, error) {
+	cookiePosition, err := p.findCookie(start, end)
+	if err != nil || cookiePosition != -1 || !p.Heuristic {
+		return cookiePosition, 0, err
+	}
+	cookiePosition, version, err := p.scanCookie(start, end)
+	if err != nil || cookiePosition == -1 {
+		return cookiePosition, 0, err
+	}
+	magic, _ := p.readAt(cookiePosition, int64(len(PYINST_MAGIC)))
+	p.logf("[!] Warning: PYINST_MAGIC not found, using the cookie at offset %d whose magic was patched to %q", cookiePosition, magic)
+	return cookiePosition, version, nil
+}
+
+// scanCookie looks for a cookie between start and end regardless of its
+// magic, returning its position and PyInstaller version. Candidates need a
+// plausible python version and layout, and a table of contents which parses.
+// Like findCookie, the last candidate of the region wins.
+func (p *Archive) scanCookie(start, end int64) (int64, int64, error) {
+	const searchChunkSize = 65536
+	candidates := 0
+	endPosition := end
+	for endPosition-start >= PYINST20_COOKIE_SIZE {
+		startPosition := max(endPosition-searchChunkSize, start)
+		// Read ahead so that cookies straddling the chunk are complete
+		data, err := p.readAt(startPosition, min(end, endPosition+PYINST21_COOKIE_SIZE)-startPosition)
+		if err != nil {
+			return -1, 0, fmt.Errorf("file read failed: %w", err)
+		}
+
+		for offs := endPosition - startPosition - 1; offs >= 0; offs-- {
+			cookiePosition := startPosition + offs
+			for _, size := range []int{PYINST21_COOKIE_SIZE, PYINST20_COOKIE_SIZE} {
+				if int(offs)+size > len(data) {
+					continue
+				}
+				overlayPosition, toc, tocLen, ok := p.plausibleCookie(data[offs:int(offs)+size], cookiePosition, start)
+				if !ok {
+					continue
+				}
+				if candidates++; candidates > maxCookieCandidates {
+					p.logf("[!] Warning: Giving up the cookie scan after %d candidates", maxCookieCandidates)
+					return -1, 0, nil
+				}
+				if p.plausibleTOC(overlayPosition, toc, tocLen) {
+					if size == PYINST21_COOKIE_SIZE {
+						return cookiePosition, 21, nil
+					}
+					return cookiePosition, 20, nil
+				}
+			}
+		}
+		endPosition = startPosition
+	}
+	return -1, 0, nil
+}
+
+// plausibleCookie checks the fields of a 2.0 or 2.1+ cookie at
+// cookiePosition, whose package can't start before start. It returns the
+// position of the package and its table of contents.
+func (p *Archive) plausibleCookie(cookie []byte, cookiePosition, start int64) (overlayPosition, toc, tocLen int64, ok bool) {
+	lengthOfPackage := int64(binary.BigEndian.Uint32(cookie[8:]))
+	toc = int64(binary.BigEndian.Uint32(cookie[12:]))
+	tocLen = int64(int32(binary.BigEndian.Uint32(cookie[16:])))
+	pythonVersion := int32(binary.BigEndian.Uint32(cookie[20:]))
+
+	if !(pythonVersion >= 26 && pythonVersion <= 39) && !(pythonVersion >= 310 && pythonVersion <= 399) {
+		return 0, 0, 0, false
+	}
+	overlayPosition = cookiePosition + int64(len(cookie)) - lengthOfPackage
+	if overlayPosition < start || tocLen < CTOC_ENTRY_SIZE || toc+tocLen > lengthOfPackage-int64(len(cookie)) {
+		return 0, 0, 0, false
+	}
+
+	if len(cookie) == PYINST21_COOKIE_SIZE {
+		// The library name may have been patched along with the magic, but
+		// it remains a NUL terminated string
+		libName := cookie[PYINST20_COOKIE_SIZE:]
+		n := bytes.IndexByte(libName, 0)
+		if n == -1 {
+			return 0, 0, 0, false
+		}
+		for _, b := range libName[:n] {
+			if b < 0x20 || b >= 0x7f {
+				return 0, 0, 0, false
+			}
+		}
+		if bytes.Count(libName[n:], []byte{0}) != len(libName)-n {
+			return 0, 0, 0, false
+		}
+	}
+	return overlayPosition, toc, tocLen, true
+}
+
+// plausibleTOC test-parses a table of contents one entry at a time, up to
+// maxTOCCheckSize bytes of it. Entries must add up to tocLen and point into
+// the package before it.
+func (p *Archive) plausibleTOC(overlayPosition, toc, tocLen int64) bool {
+	for pos := int64(0); pos < tocLen; {
+		if pos >= maxTOCCheckSize {
+			return true
+		}
+		if tocLen-pos < CTOC_ENTRY_SIZE {
+			return false
+		}
+		header, err := p.readAt(overlayPosition+toc+pos, CTOC_ENTRY_SIZE)
+		if err != nil {
+			return false
+		}
+		entrySize := int64(int32(binary.BigEndian.Uint32(header)))
+		entryPosition := int64(binary.BigEndian.Uint32(header[4:]))
+		dataSize := int64(binary.BigEndian.Uint32(header[8:]))
+		compressionFlag := header[16]
+		typecode := header[17]
+		if entrySize < CTOC_ENTRY_SIZE || entrySize > tocLen-pos ||
+			entryPosition+dataSize > toc || compressionFlag > 1 ||
+			bytes.IndexByte([]byte(CTOC_ENTRY_TYPECODES), typecode) == -1 {
+			return false
+		}
+
+		nameLen := min(entrySize, maxTOCCheckSize-pos) - CTOC_ENTRY_SIZE
+		name, err := p.readAt(overlayPosition+toc+pos+CTOC_ENTRY_SIZE, max(nameLen, 0))
+		if err != nil {
+			return false
+		}
+		for _, b := range bytes.TrimRight(name, "\x00") {
+			if b < 0x20 {
+				return false
+			}
+		}
+		pos += entrySize
+	}
+	return true
+}
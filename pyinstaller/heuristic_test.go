@@ -0,0 +1,119 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// patchMagic replaces the PYINST_MAGIC of data by the given bytes
+func patchMagic(data []byte, magic string) []byte {
+	i := bytes.LastIndex(data, PYINST_MAGIC[:])
+	patched := append([]byte(nil), data...)
+	copy(patched[i:], magic)
+	return patched
+}
+
+func TestHeuristicCookie(t *testing.T) {
+	entries := []testEntry{
+		{"main", 's', []byte("code"), true},
+		{"data.txt", 'x', []byte("data"), false},
+	}
+	tests := []struct {
+		name       string
+		data       []byte
+		cookieSize int64
+		version    int64
+	}{
+		{"2.1+", patchMagic(buildArchive(entries), "XYZ\x0c\x0b\x0a\x0b\x0e"), PYINST21_COOKIE_SIZE, 21},
+		{"2.0", patchMagic(buildArchiveFor(entries, 20, 27), "\x00\x00\x00\x00\x00\x00\x00\x00"), PYINST20_COOKIE_SIZE, 20},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			arch := NewArchive(bytes.NewReader(test.data), int64(len(test.data)), "patched.bin")
+			if err := arch.CheckFile(); !errors.Is(err, ErrMissingCookie) {
+				t.Fatalf("CheckFile without Heuristic: %v", err)
+			}
+
+			arch.Heuristic = true
+			arch.Logf = t.Logf
+			if err := arch.CheckFile(); err != nil {
+				t.Fatal(err)
+			}
+			if arch.cookiePosition != int64(len(test.data))-test.cookieSize || arch.pyInstVersion != test.version {
+				t.Errorf("cookie at %d of version %d, want the %d byte cookie at the end of version %d",
+					arch.cookiePosition, arch.pyInstVersion, test.cookieSize, test.version)
+			}
+			if err := arch.GetCArchiveInfo(); err != nil {
+				t.Fatal(err)
+			}
+			if err := arch.ParseTOC(); err != nil {
+				t.Fatal(err)
+			}
+			out := NewMemSink()
+			if err := arch.ExtractFiles(out); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := out.File("data.txt"); string(got) != "data" {
+				t.Errorf("data.txt = %q", got)
+			}
+		})
+	}
+}
+
+func TestHeuristicFalsePositives(t *testing.T) {
+	// Random data holding cookies with a patched magic, a sane layout and a
+	// table of contents which is noise
+	rng := rand.New(rand.NewSource(1))
+	random := make([]byte, 1<<20)
+	rng.Read(random)
+	for i := 0; i < 64; i++ {
+		cookie := random[rng.Intn(len(random)-PYINST21_COOKIE_SIZE):]
+		copy(cookie, "MEI\x0c\x0b\x0a\x0b\x0f")
+		binary.BigEndian.PutUint32(cookie[8:], 0x10000)
+		binary.BigEndian.PutUint32(cookie[12:], 0x100)
+		binary.BigEndian.PutUint32(cookie[16:], 0x1000)
+		binary.BigEndian.PutUint32(cookie[20:], 311)
+		copy(cookie[24:], append([]byte("libpython3.11.so.1.0"), make([]byte, 44)...))
+	}
+
+	// Each offset of a repeating pattern is a candidate with a large table
+	// of contents, which used to be read whole for every one of them
+	var pattern bytes.Buffer
+	for pattern.Len() < 4<<20 {
+		binary.Write(&pattern, binary.BigEndian, []uint32{0x8000, 311, 0x10000, 0x100})
+	}
+
+	for name, data := range map[string][]byte{"random": random, "pattern": pattern.Bytes()} {
+		arch := NewArchive(bytes.NewReader(data), int64(len(data)), name)
+		arch.Heuristic = true
+		if err := arch.CheckFile(); !errors.Is(err, ErrMissingCookie) {
+			t.Errorf("%s: CheckFile = %v, cookie at %d", name, err, arch.cookiePosition)
+		}
+	}
+}
+
+func TestHeuristicMachOSlices(t *testing.T) {
+	// The first slice has an intact 2.1+ cookie, the second a patched 2.0
+	// cookie which must not change the version of the first
+	entries := []testEntry{{"data.txt", 'x', []byte("data"), false}}
+	data := buildFatMachO([][]byte{
+		buildArchive(entries),
+		patchMagic(buildArchiveFor(entries, 20, 27), "XYZ\x0c\x0b\x0a\x0b\x0e"),
+	})
+	arch := NewArchive(bytes.NewReader(data), int64(len(data)), "fat.bin")
+	arch.Heuristic = true
+	arch.Logf = t.Logf
+	if err := arch.CheckFile(); err != nil {
+		t.Fatal(err)
+	}
+	slices := arch.MachOSlices()
+	if len(slices) != 2 || !slices[0].HasArchive || !slices[1].HasArchive {
+		t.Fatalf("slices %+v", slices)
+	}
+	if arch.pyInstVersion != 21 || arch.archiveEnd != slices[0].Offset+slices[0].Size {
+		t.Errorf("version %d, archive ending at %d; want those of the first slice", arch.pyInstVersion, arch.archiveEnd)
+	}
+}
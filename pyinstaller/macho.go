@@ -0,0 +1,84 @@
+package pyinstaller
+
+import (
+	"debug/macho"
+	"io"
+)
+
+// LC_CODE_SIGNATURE is the load command locating the code signature, which
+// codesign appends after the archive
+const LC_CODE_SIGNATURE = 0x1d
+
+// MachOSlice is an architecture of a Mach-O universal binary, or the whole
+// file for thin Mach-O files.
+type MachOSlice struct {
+	Cpu    string
+	Offset int64
+	// Size stops at the code signature of the slice
+	Size       int64
+	HasArchive bool
+}
+
+// MachOSlices returns the slices found by CheckFile, or nil if the input
+// isn't a Mach-O file. The archive is read from the first slice carrying
+// one.
+func (p *Archive) MachOSlices() []MachOSlice {
+	return p.machOSlices
+}
+
+// readMachOSlices returns the slices of a Mach-O or universal binary
+func (p *Archive) readMachOSlices() []MachOSlice {
+	r := io.NewSectionReader(p.r, 0, p.fileSize)
+	if fat, err := macho.NewFatFile(r); err == nil {
+		defer fat.Close()
+		var slices []MachOSlice
+		for _, arch := range fat.Arches {
+			slices = append(slices, MachOSlice{
+				Cpu:    cpuName(arch.Cpu),
+				Offset: int64(arch.Offset),
+				Size:   codeSignatureStart(arch.File, int64(arch.Size)),
+			})
+		}
+		return slices
+	}
+	if f, err := macho.NewFile(r); err == nil {
+		defer f.Close()
+		return []MachOSlice{{Cpu: cpuName(f.Cpu), Size: codeSignatureStart(f, p.fileSize)}}
+	}
+	return nil
+}
+
+// codeSignatureStart returns the offset of the code signature within a
+// Mach-O file of the given size, or the size if it isn't signed
+func codeSignatureStart(f *macho.File, size int64) int64 {
+	for _, load := range f.Loads {
+		raw := load.Raw()
+		if len(raw) < 16 || f.ByteOrder.Uint32(raw) != LC_CODE_SIGNATURE {
+			continue
+		}
+		// linkedit_data_command: cmd, cmdsize, dataoff, datasize
+		if dataoff := int64(f.ByteOrder.Uint32(raw[8:])); dataoff > 0 && dataoff < size {
+			return dataoff
+		}
+	}
+	return size
+}
+
+// cpuName returns the name Apple tools use for a CPU type
+func cpuName(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.Cpu386:
+		return "i386"
+	case macho.CpuAmd64:
+		return "x86_64"
+	case macho.CpuArm:
+		return "arm"
+	case macho.CpuArm64:
+		return "arm64"
+	case macho.CpuPpc:
+		return "ppc"
+	case macho.CpuPpc64:
+		return "ppc64"
+	}
+	return cpu.String()
+}
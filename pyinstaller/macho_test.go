@@ -0,0 +1,51 @@
+package pyinstaller
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMachOSlices(t *testing.T) {
+	// The code signatures hold a decoy PYINST_MAGIC
+	tests := []struct {
+		file    string
+		slices  []MachOSlice
+		cookies []int64
+	}{
+		{"thin.macho", []MachOSlice{{"x86_64", 0, 381, true}}, []int64{293}},
+		{"signed.macho", []MachOSlice{{"arm64", 0, 381, true}}, []int64{293}},
+		{"fat.macho", []MachOSlice{{"x86_64", 512, 320, false}, {"arm64", 1028, 381, true}}, []int64{-1, 1321}},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + test.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			arch := openArchive(t, data)
+			if got := arch.MachOSlices(); !reflect.DeepEqual(got, test.slices) {
+				t.Errorf("MachOSlices = %+v, want %+v", got, test.slices)
+			}
+			for i, slice := range test.slices {
+				got, _, err := arch.locateCookie(slice.Offset, slice.Offset+slice.Size)
+				if err != nil || got != test.cookies[i] {
+					t.Errorf("cookie of slice %s at %d, %v, want %d", slice.Cpu, got, err, test.cookies[i])
+				}
+			}
+
+			out := NewMemSink()
+			if err := arch.ExtractFiles(out); err != nil {
+				t.Fatal(err)
+			}
+			if got, ok := out.File("data.txt"); !ok || string(got) != "macho" {
+				t.Errorf("data.txt = %q, %v", got, ok)
+			}
+		})
+	}
+
+	arch := openArchive(t, buildArchive([]testEntry{{"data.txt", 'x', nil, false}}))
+	if slices := arch.MachOSlices(); slices != nil {
+		t.Errorf("ELF file has slices %+v", slices)
+	}
+}
@@ -0,0 +1,184 @@
+package pyinstaller
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Dependency is an ARCHIVE_ITEM_DEPENDENCY entry of a MERGE build, a file
+// stored once in the archive or onedir folder of a sibling executable.
+type Dependency struct {
+	// Path is the sibling executable, relative to this one
+	Path string
+	// Filename is the name of the file within the sibling
+	Filename string
+	// Source is where ExtractFiles found the file, empty if it wasn't found
+	Source string
+}
+
+// Dependencies returns the dependencies found by ParseTOC.
+func (p *Archive) Dependencies() []Dependency {
+	return p.dependencies
+}
+
+// parseDependency splits the "path:filename" name of a dependency entry the
+// way the bootloader does
+func parseDependency(name string) Dependency {
+	path, filename, _ := strings.Cut(name, ":")
+	return Dependency{Path: path, Filename: filename}
+}
+
+// siblingPath returns the sibling executable holding dep, preferring those
+// passed in Siblings over the ones next to the input file. The path comes
+// from the archive, so it is sanitized to stay below the input's directory.
+func (p *Archive) siblingPath(dep Dependency) string {
+	depPath := sanitizePath(dep.Path)
+	base := path.Base(depPath)
+	for _, sibling := range p.Siblings {
+		if filepath.Base(sibling) == base {
+			return sibling
+		}
+	}
+	return filepath.Join(filepath.Dir(p.Name), filepath.FromSlash(depPath))
+}
+
+// allowedSource reports whether filePath, once its symlinks are resolved,
+// lies below the directory of the input or of an executable named in
+// Siblings
+func (p *Archive) allowedSource(filePath string) bool {
+	for _, sibling := range p.Siblings {
+		if filePath == sibling {
+			return true
+		}
+	}
+	resolved, err := filepath.EvalSymlinks(filePath)
+	if err != nil {
+		return false
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return false
+	}
+	roots := []string{filepath.Dir(p.Name)}
+	for _, sibling := range p.Siblings {
+		roots = append(roots, filepath.Dir(sibling))
+	}
+	for _, root := range roots {
+		root, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		if root, err = filepath.Abs(root); err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractDependencies pulls the dependencies of a MERGE build from the
+// sibling executables, either from their onedir folder or their CArchive.
+func (p *Archive) extractDependencies() {
+	siblings := make(map[string]*Archive)
+	defer func() {
+		for _, sibling := range siblings {
+			if sibling != nil {
+				sibling.Close()
+			}
+		}
+	}()
+
+	for i := range p.dependencies {
+		dep := &p.dependencies[i]
+		siblingPath := p.siblingPath(*dep)
+		entryPath := sanitizePath(dep.Filename)
+
+		// Onedir siblings keep the file next to them, or in _internal from
+		// PyInstaller 6
+		siblingDir := filepath.Dir(siblingPath)
+		found := false
+		for _, dir := range []string{siblingDir, filepath.Join(siblingDir, "_internal")} {
+			filePath := filepath.Join(dir, filepath.FromSlash(entryPath))
+			if !p.allowedSource(filePath) {
+				continue
+			}
+			if data, err := os.ReadFile(filePath); err == nil {
+				p.fileSource = filePath
+				p.writeRawData(p.ensureUnique(entryPath, ""), data)
+				p.fileSource = ""
+				dep.Source = filePath
+				found = true
+				break
+			}
+		}
+		if found {
+			p.logf("[+] Dependency %s found in %s", dep.Filename, dep.Source)
+			continue
+		}
+
+		if !p.allowedSource(siblingPath) {
+			p.logf("[!] Warning: Dependency %s not found: %s is missing or outside the input's directory", dep.Filename, siblingPath)
+			continue
+		}
+		sibling, ok := siblings[siblingPath]
+		if !ok {
+			var err error
+			if sibling, err = openSibling(siblingPath, p.Heuristic); err != nil {
+				p.logf("[!] Warning: Dependency %s not found: %v", dep.Filename, err)
+			}
+			siblings[siblingPath] = sibling
+		}
+		if sibling == nil {
+			continue
+		}
+
+		data, err := sibling.readDependency(dep.Filename)
+		if err != nil {
+			p.logf("[!] Warning: Dependency %s not found in %s: %v", dep.Filename, siblingPath, err)
+			continue
+		}
+		p.fileSource = siblingPath
+		p.writeRawData(p.ensureUnique(entryPath, ""), data)
+		p.fileSource = ""
+		dep.Source = siblingPath
+		p.logf("[+] Dependency %s found in %s", dep.Filename, dep.Source)
+	}
+}
+
+// openSibling opens and parses the CArchive of a sibling executable
+func openSibling(path string, heuristic bool) (*Archive, error) {
+	sibling, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sibling.Heuristic = heuristic
+	if err := sibling.CheckFile(); err != nil {
+		sibling.Close()
+		return nil, err
+	}
+	if err := sibling.GetCArchiveInfo(); err != nil {
+		sibling.Close()
+		return nil, err
+	}
+	if err := sibling.ParseTOC(); err != nil {
+		sibling.Close()
+		return nil, err
+	}
+	return sibling, nil
+}
+
+// readDependency returns the contents of the named file entry
+func (p *Archive) readDependency(name string) ([]byte, error) {
+	for _, entry := range p.tableOfContents {
+		if entry.Name != name || entry.TypeCompressedData == 'd' {
+			continue
+		}
+		return p.ReadEntry(entry)
+	}
+	return nil, errors.New("no such entry")
+}
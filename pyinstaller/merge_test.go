@@ -0,0 +1,75 @@
+package pyinstaller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractDependencies(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(dir, outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A onedir sibling next to the input and one holding the file in its
+	// archive
+	if err := os.WriteFile(filepath.Join(dir, "shared.txt"), []byte("shared"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	sibling := buildArchive([]testEntry{{"lib/dep.txt", 'x', []byte("dep"), true}})
+	if err := os.WriteFile(filepath.Join(dir, "other"), sibling, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	input := filepath.Join(dir, "app")
+	data := buildArchive([]testEntry{
+		{"onedir:shared.txt", 'd', nil, false},
+		{"other:lib/dep.txt", 'd', nil, false},
+		{filepath.ToSlash(rel) + "/anything:secret", 'd', nil, false},
+		{"../../../../../../../../etc/anything:passwd", 'd', nil, false},
+	})
+	if err := os.WriteFile(input, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	arch, err := Open(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer arch.Close()
+	arch.Logf = t.Logf
+	if err := arch.CheckFile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := arch.GetCArchiveInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if err := arch.ParseTOC(); err != nil {
+		t.Fatal(err)
+	}
+	out := NewMemSink()
+	if err := arch.ExtractFiles(out); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"shared.txt": "shared", "lib/dep.txt": "dep"} {
+		if got, ok := out.File(name); !ok || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, ok, want)
+		}
+	}
+	for _, name := range []string{"secret", "passwd"} {
+		if _, ok := out.File(name); ok {
+			t.Errorf("%s was copied from outside the input's directory", name)
+		}
+	}
+	deps := arch.Dependencies()
+	if deps[2].Source != "" || deps[3].Source != "" {
+		t.Errorf("sources %q and %q, want none", deps[2].Source, deps[3].Source)
+	}
+}
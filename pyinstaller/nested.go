@@ -0,0 +1,139 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"strings"
+)
+
+// nestedCandidate is an extracted file carrying PYINST_MAGIC
+type nestedCandidate struct {
+	path string
+	// data is only kept for sinks which can't read files back
+	data []byte
+}
+
+// Nested returns the PyInstaller executables found among the extracted
+// files when NestingDepth is set. Their own Nested form the nesting tree.
+func (p *Archive) Nested() []*Archive {
+	return p.nested
+}
+
+// NestingLevel returns 0 for the input file, 1 for the executables nested
+// in it and so on.
+func (p *Archive) NestingLevel() int {
+	return p.nestingLevel
+}
+
+// checkNested queues an extracted file for extractNested if it carries
+// PYINST_MAGIC. Only its path is kept when the sink can read files back.
+func (p *Archive) checkNested(path string, data []byte) {
+	if p.NestingDepth == 0 || !bytes.Contains(data, PYINST_MAGIC[:]) {
+		return
+	}
+	if canOpen(p.out) {
+		data = nil
+	}
+	p.nestedCandidates = append(p.nestedCandidates, nestedCandidate{path, data})
+}
+
+// openCandidate returns a reader of a queued file and its size
+func (p *Archive) openCandidate(candidate nestedCandidate) (io.ReaderAt, int64, error) {
+	if candidate.data != nil {
+		return bytes.NewReader(candidate.data), int64(len(candidate.data)), nil
+	}
+	return p.out.(Opener).Open(candidate.path)
+}
+
+// hashFile returns the SHA-256 of size bytes from r
+func hashFile(r io.ReaderAt, size int64) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, size)); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// extractNested extracts the queued executables into <path>_extracted. An
+// executable met before, including one of the enclosing archives, is only
+// reported.
+func (p *Archive) extractNested() {
+	candidates := p.nestedCandidates
+	p.nestedCandidates = nil
+	if len(candidates) == 0 {
+		return
+	}
+
+	if p.seen == nil {
+		p.seen = make(map[[sha256.Size]byte]string)
+		if sum, err := hashFile(p.r, p.fileSize); err == nil {
+			p.seen[sum] = p.Name
+		}
+	}
+
+	for _, candidate := range candidates {
+		p.extractCandidate(candidate)
+	}
+}
+
+// extractCandidate extracts a queued executable unless it was met before or
+// the nesting depth is reached
+func (p *Archive) extractCandidate(candidate nestedCandidate) {
+	r, size, err := p.openCandidate(candidate)
+	if err != nil {
+		p.logf("[!] Error: Failed to read back %s: %v", candidate.path, err)
+		return
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	// Name nested executables by their path from the outermost extraction
+	// directory
+	outPath := p.outPrefix + candidate.path
+	sum, err := hashFile(r, size)
+	if err != nil {
+		p.logf("[!] Error: Failed to read back %s: %v", outPath, err)
+		return
+	}
+	if name, ok := p.seen[sum]; ok {
+		p.logf("[!] Warning: %s is the same executable as %s, not extracting it again", outPath, name)
+		return
+	}
+	p.seen[sum] = outPath
+
+	if p.nestingLevel >= p.NestingDepth {
+		p.logf("[!] Warning: Not extracting nested executable %s, nesting depth limit reached", outPath)
+		return
+	}
+
+	child := NewArchive(r, size, outPath)
+	child.Logf = p.Logf
+	child.Disassemble = p.Disassemble
+	child.NestingDepth = p.NestingDepth
+	child.Heuristic = p.Heuristic
+	child.nestingLevel = p.nestingLevel + 1
+	child.seen = p.seen
+	child.outPrefix = outPath + "_extracted/"
+
+	for _, step := range []func() error{child.CheckFile, child.GetCArchiveInfo, child.ParseTOC} {
+		if err := step(); err != nil {
+			p.logf("[!] Warning: %s carries the PyInstaller magic but isn't an archive: %v", outPath, err)
+			return
+		}
+	}
+
+	p.logf("[+] Extracting nested executable %s", outPath)
+	prefix := strings.TrimPrefix(child.outPrefix, p.outPrefix)
+	if err := child.ExtractFiles(&prefixSink{p.out, prefix}); err != nil {
+		p.logf("[!] Failed to extract nested executable %s: %v", outPath, err)
+		return
+	}
+	for _, file := range child.extractedFiles {
+		p.extractedFiles = append(p.extractedFiles, ExtractedFile{prefix + file.Name, file.Source})
+	}
+	p.nested = append(p.nested, child)
+}
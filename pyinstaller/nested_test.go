@@ -0,0 +1,98 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNestedArchives(t *testing.T) {
+	inner := buildArchive([]testEntry{{"inner.txt", 'x', []byte("innermost"), true}})
+	middle := buildArchive([]testEntry{{"inner.exe", 'b', inner, true}})
+	outer := buildArchive([]testEntry{{"middle.exe", 'b', middle, true}})
+
+	for _, sink := range []string{"mem", "dir"} {
+		t.Run(sink, func(t *testing.T) {
+			var out Sink = NewMemSink()
+			if sink == "dir" {
+				out = &DirSink{Root: t.TempDir()}
+			}
+			arch := openArchive(t, outer)
+			arch.NestingDepth = 2
+			if err := arch.ExtractFiles(out); err != nil {
+				t.Fatal(err)
+			}
+
+			const path = "middle.exe_extracted/inner.exe_extracted/inner.txt"
+			var names []string
+			for _, file := range arch.ExtractedFiles() {
+				names = append(names, file.Name)
+			}
+			want := []string{"middle.exe", "middle.exe_extracted/inner.exe", path}
+			if !reflect.DeepEqual(names, want) {
+				t.Errorf("extracted %q, want %q", names, want)
+			}
+			r, _, err := out.(Opener).Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c, ok := r.(interface{ Close() error }); ok {
+				defer c.Close()
+			}
+			got := make([]byte, len("innermost"))
+			if _, err := r.ReadAt(got, 0); err != nil || string(got) != "innermost" {
+				t.Errorf("%s = %q, %v", path, got, err)
+			}
+
+			if len(arch.Nested()) != 1 || len(arch.Nested()[0].Nested()) != 1 {
+				t.Fatalf("nesting tree %v", arch.Nested())
+			}
+			if level := arch.Nested()[0].Nested()[0].NestingLevel(); level != 2 {
+				t.Errorf("nesting level %d, want 2", level)
+			}
+		})
+	}
+
+	t.Run("depth limit", func(t *testing.T) {
+		arch := openArchive(t, outer)
+		arch.NestingDepth = 1
+		out := NewMemSink()
+		if err := arch.ExtractFiles(out); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"middle.exe", "middle.exe_extracted/inner.exe"}
+		if names := out.Names(); !reflect.DeepEqual(names, want) {
+			t.Errorf("extracted %q, want %q", names, want)
+		}
+	})
+}
+
+func TestNestedArchiveCycle(t *testing.T) {
+	// The only entry spans the whole file, so the archive holds itself
+	data := buildArchive([]testEntry{{"self.exe", 'b', nil, false}})
+	cookie := data[len(data)-PYINST21_COOKIE_SIZE:]
+	data = data[len(data)-int(binary.BigEndian.Uint32(cookie[8:])):]
+	binary.BigEndian.PutUint32(data[8:], uint32(len(data)))
+	binary.BigEndian.PutUint32(data[12:], uint32(len(data)))
+
+	var logs strings.Builder
+	arch := openArchive(t, data)
+	arch.Logf = func(format string, args ...any) { fmt.Fprintf(&logs, format+"\n", args...) }
+	arch.NestingDepth = 5
+	out := NewMemSink()
+	if err := arch.ExtractFiles(out); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := out.File("self.exe"); !bytes.Equal(got, data) {
+		t.Fatalf("self.exe isn't a copy of the archive")
+	}
+	if names := out.Names(); !reflect.DeepEqual(names, []string{"self.exe"}) {
+		t.Errorf("extracted %q", names)
+	}
+	if !strings.Contains(logs.String(), "self.exe is the same executable as test.bin") {
+		t.Errorf("cycle not reported:\n%s", logs.String())
+	}
+}
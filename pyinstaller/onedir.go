@@ -0,0 +1,165 @@
+package pyinstaller
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ONEDIR_MANIFEST records where every file of a onedir extraction came
+// from, one "name <- source" line per file
+const ONEDIR_MANIFEST = "onedir.manifest"
+
+// ExtractedFile is a file written by ExtractFiles. Source is the input file
+// for CArchive and PYZ entries, and the path relative to OnedirRoot for
+// files copied from a onedir build.
+type ExtractedFile struct {
+	Name   string
+	Source string
+}
+
+// ExtractedFiles returns the files written by ExtractFiles.
+func (p *Archive) ExtractedFiles() []ExtractedFile {
+	return p.extractedFiles
+}
+
+// FindOnedirExecutable returns the bootloader executable of a onedir build,
+// the file at the top of dir carrying a CArchive. A file named like dir is
+// preferred when several do.
+func FindOnedirExecutable(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var found []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		candidate := filepath.Join(dir, entry.Name())
+		arch, err := Open(candidate)
+		if err != nil {
+			continue
+		}
+		if arch.CheckFile() == nil && arch.GetCArchiveInfo() == nil {
+			found = append(found, candidate)
+		}
+		arch.Close()
+	}
+	if len(found) == 0 {
+		return "", fmt.Errorf("%w: no executable in %s", ErrMissingCookie, dir)
+	}
+
+	// filepath.Base of "." is ".", the folder name is needed
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	dirName := filepath.Base(absDir)
+	for _, candidate := range found {
+		name := filepath.Base(candidate)
+		if strings.TrimSuffix(name, filepath.Ext(name)) == dirName {
+			return candidate, nil
+		}
+	}
+	return found[0], nil
+}
+
+// contentsDirectory returns the folder holding the onedir payload, relative
+// to OnedirRoot. PyInstaller 6 names it with the pyi-contents-directory
+// option, older releases put everything next to the executable.
+func (p *Archive) contentsDirectory() string {
+	for _, option := range p.runtimeOptions {
+		if option.Name == "pyi-contents-directory" && option.Value != "" {
+			return path.Clean(strings.ReplaceAll(option.Value, "\\", "/"))
+		}
+	}
+	if info, err := os.Stat(filepath.Join(p.OnedirRoot, "_internal")); err == nil && info.IsDir() {
+		return "_internal"
+	}
+	return "."
+}
+
+// copyOnedir copies the files of OnedirRoot besides the executable into the
+// sink. The contents directory is merged with the CArchive entries at the
+// root, the same way the bootloader sees them at run time.
+func (p *Archive) copyOnedir() {
+	contentsDir := p.contentsDirectory()
+	executable, _ := filepath.Abs(p.Name)
+	// The extraction directory may lie inside the onedir folder
+	var outDir string
+	if s, ok := p.out.(*DirSink); ok {
+		outDir, _ = filepath.Abs(s.Root)
+	}
+	p.logf("[+] Copying onedir files from %s", filepath.Join(p.OnedirRoot, filepath.FromSlash(contentsDir)))
+
+	err := filepath.WalkDir(p.OnedirRoot, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			p.logf("[!] Error: Failed to read %s: %v", filePath, err)
+			return nil
+		}
+		abs, _ := filepath.Abs(filePath)
+		if d.IsDir() {
+			if abs == outDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if abs == executable {
+			return nil
+		}
+		rel, err := filepath.Rel(p.OnedirRoot, filePath)
+		if err != nil {
+			return nil
+		}
+		source := filepath.ToSlash(rel)
+		name := source
+		if contentsDir != "." {
+			if trimmed, ok := strings.CutPrefix(source, contentsDir+"/"); ok {
+				name = trimmed
+			}
+		}
+		name = p.ensureUnique(sanitizePath(name), "")
+
+		p.fileSource = source
+		defer func() { p.fileSource = "" }()
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(filePath)
+			if err != nil {
+				p.logf("[!] Error: Failed to read %s: %v", filePath, err)
+				return nil
+			}
+			p.writeSymlink(name, filepath.ToSlash(target))
+			return nil
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			p.logf("[!] Error: Failed to read %s: %v", filePath, err)
+			return nil
+		}
+		p.writeRawData(name, data)
+		if isEmbeddedZip(name) {
+			if err := p.extractZip(name, data, 0); err != nil {
+				p.logf("[!] Failed to extract zip %s: %v", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		p.logf("[!] Error: Failed to copy onedir files: %v", err)
+	}
+}
+
+// writeOnedirManifest writes ONEDIR_MANIFEST, listing the source of every
+// extracted file
+func (p *Archive) writeOnedirManifest() {
+	var sb strings.Builder
+	for _, file := range p.extractedFiles {
+		fmt.Fprintf(&sb, "%s <- %s\n", file.Name, file.Source)
+	}
+	manifestPath := p.ensureUnique(ONEDIR_MANIFEST, "")
+	p.writeRawData(manifestPath, []byte(sb.String()))
+}
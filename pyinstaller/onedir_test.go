@@ -0,0 +1,92 @@
+package pyinstaller
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+}
+
+func TestOnedir(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "app")
+	files := map[string][]byte{
+		"app":                 buildArchive([]testEntry{{"pyi-contents-directory _internal", 'o', nil, false}, {"main", 's', []byte("code"), false}}),
+		"Updater":             buildArchive([]testEntry{{"updater", 's', []byte("code"), false}}),
+		"_internal/libfoo.so": []byte("library"),
+		"README.txt":          []byte("readme"),
+		// Left by an earlier run inside the folder
+		"app_extracted/stale.txt": []byte("stale"),
+	}
+	for name, data := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Run from inside the folder, which is named after the executable
+	// sorting after Updater
+	chdir(t, root)
+
+	exeName, err := FindOnedirExecutable(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exeName != "app" {
+		t.Fatalf("FindOnedirExecutable = %s, want app", exeName)
+	}
+
+	arch, err := Open(exeName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer arch.Close()
+	arch.Logf = t.Logf
+	arch.OnedirRoot = "."
+	for _, step := range []func() error{arch.CheckFile, arch.GetCArchiveInfo, arch.ParseTOC} {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := NewDirSink("app_extracted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := arch.ExtractFiles(out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ExtractedFile{
+		{"main.pyc", "app"},
+		{"README.txt", "README.txt"},
+		{"Updater", "Updater"},
+		{"libfoo.so", "_internal/libfoo.so"},
+		{ONEDIR_MANIFEST, "app"},
+	}
+	if got := arch.ExtractedFiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("extracted %q, want %q", got, want)
+	}
+	manifest, err := os.ReadFile(filepath.Join("app_extracted", ONEDIR_MANIFEST))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantManifest = "main.pyc <- app\nREADME.txt <- README.txt\nUpdater <- Updater\nlibfoo.so <- _internal/libfoo.so\n"
+	if string(manifest) != wantManifest {
+		t.Errorf("%s = %q, want %q", ONEDIR_MANIFEST, manifest, wantManifest)
+	}
+}
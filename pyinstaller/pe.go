@@ -0,0 +1,166 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"io"
+	"sort"
+	"time"
+)
+
+// PEInfo describes the layout of a PE input.
+type PEInfo struct {
+	// OverlayOffset is the end of the last section, where data appended to
+	// the image starts
+	OverlayOffset int64
+
+	// CertificateOffset and CertificateSize locate the certificate table
+	// holding the Authenticode signature. Both are zero for unsigned files.
+	CertificateOffset int64
+	CertificateSize   int64
+
+	// Signature is nil if the file is unsigned or the signature couldn't be
+	// decoded
+	Signature *Authenticode
+
+	// Resources is nil if the file has no resource directory
+	Resources *PEResources
+
+	// AppendedSize counts the bytes following the certificate table
+	AppendedSize int64
+}
+
+// PE returns the PE layout found by CheckFile, or nil if the input isn't a
+// PE file.
+func (p *Archive) PE() *PEInfo {
+	return p.pe
+}
+
+// readPE parses the PE headers and certificate table of the input
+func (p *Archive) readPE() *PEInfo {
+	// debug/pe also accepts bare COFF objects, which any input starting with
+	// zeros looks like
+	dos, err := p.readAt(0, 0x40)
+	if err != nil || !bytes.HasPrefix(dos, []byte("MZ")) {
+		return nil
+	}
+	signature, err := p.readAt(int64(binary.LittleEndian.Uint32(dos[0x3c:])), 4)
+	if err != nil || !bytes.Equal(signature, []byte("PE\x00\x00")) {
+		return nil
+	}
+
+	f, err := pe.NewFile(io.NewSectionReader(p.r, 0, p.fileSize))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	info := &PEInfo{}
+	var dirs []pe.DataDirectory
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		info.OverlayOffset = int64(oh.SizeOfHeaders)
+		dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+	case *pe.OptionalHeader64:
+		info.OverlayOffset = int64(oh.SizeOfHeaders)
+		dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+	}
+	for _, section := range f.Sections {
+		if section.Size == 0 {
+			continue
+		}
+		info.OverlayOffset = max(info.OverlayOffset, int64(section.Offset)+int64(section.Size))
+	}
+	p.logf("[+] PE overlay starts at offset %d", info.OverlayOffset)
+	p.readResources(f, info)
+
+	if len(dirs) <= pe.IMAGE_DIRECTORY_ENTRY_SECURITY || dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY].Size == 0 {
+		return info
+	}
+	// The certificate table is located by file offset, not by RVA
+	certDir := dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY]
+	offset, size := int64(certDir.VirtualAddress), int64(certDir.Size)
+	if offset+size > p.fileSize {
+		p.logf("[!] Warning: Certificate table lies outside the file")
+		return info
+	}
+	info.CertificateOffset, info.CertificateSize = offset, size
+	info.AppendedSize = p.fileSize - offset - size
+	if info.AppendedSize > 0 {
+		p.logf("[!] Warning: %d bytes appended after the Authenticode signature", info.AppendedSize)
+	}
+
+	table, err := p.readAt(offset, size)
+	if err != nil {
+		return info
+	}
+	if info.Signature, err = ParseCertificateTable(table); err != nil {
+		p.logf("[!] Warning: Failed to decode the Authenticode signature: %v", err)
+		return info
+	}
+	for _, signer := range info.Signature.Signers {
+		p.logf("[+] Authenticode signer: %s", signer.Subject)
+		p.logf("[+]     issuer: %s, serial number: %s", signer.Issuer, signer.SerialNumber)
+		if !signer.SigningTime.IsZero() {
+			p.logf("[+]     signing time: %s", signer.SigningTime.UTC().Format(time.RFC3339))
+		}
+		for _, t := range signer.Timestamps {
+			p.logf("[+]     timestamp: %s", t.UTC().Format(time.RFC3339))
+		}
+	}
+	return info
+}
+
+// readResources decodes the resources of the input, logging the attribution
+// relevant fields
+func (p *Archive) readResources(f *pe.File, info *PEInfo) {
+	resources, err := readPEResources(f)
+	if err != nil {
+		p.logf("[!] Warning: Failed to walk the PE resource directory: %v", err)
+	}
+	if len(resources) == 0 {
+		return
+	}
+	info.Resources = parsePEResources(resources)
+
+	if v := info.Resources.Version; v != nil {
+		p.logf("[+] PE file version: %s, product version: %s", v.FileVersion, v.ProductVersion)
+		var tables []string
+		for lang := range v.Strings {
+			tables = append(tables, lang)
+		}
+		sort.Strings(tables)
+		for _, lang := range tables {
+			table := v.Strings[lang]
+			for _, key := range []string{"CompanyName", "ProductName", "FileDescription", "OriginalFilename"} {
+				if value := table[key]; value != "" {
+					p.logf("[+]     %s: %s", key, value)
+				}
+			}
+		}
+	}
+	if level := info.Resources.ExecutionLevel; level != "" {
+		p.logf("[+] Manifest requests execution level %s", level)
+	}
+	if n := len(info.Resources.Icons); n != 0 {
+		p.logf("[+] Found %d icon groups", n)
+	}
+}
+
+// checkPEOverlay flags archives which don't start at the PE overlay or lie
+// after the signature
+func (p *Archive) checkPEOverlay() {
+	if p.pe == nil {
+		return
+	}
+	switch {
+	case p.overlayPosition > p.pe.OverlayOffset:
+		p.logf("[!] Warning: %d bytes between the end of the PE sections and the archive", p.overlayPosition-p.pe.OverlayOffset)
+	case p.overlayPosition < p.pe.OverlayOffset:
+		p.logf("[!] Warning: Archive starts inside the PE sections, at offset %d", p.overlayPosition)
+	}
+	if p.pe.CertificateSize != 0 && p.overlayPosition >= p.pe.CertificateOffset+p.pe.CertificateSize {
+		p.logf("[!] Warning: Archive was appended after the Authenticode signature")
+	}
+}
@@ -0,0 +1,42 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadPE(t *testing.T) {
+	archive := buildArchive([]testEntry{{"main", 's', []byte("code"), true}})
+	image := buildPE(make([]byte, 0x200), 0, 0, nil)
+
+	// A bare COFF header, which debug/pe accepts as well
+	coff := append(make([]byte, 0x200), archive...)
+	notPE := append([]byte(nil), image...)
+	copy(notPE[0x40:], "NE\x00\x00")
+
+	tests := []struct {
+		name    string
+		data    []byte
+		isPE    bool
+		overlay int64
+	}{
+		{"pe", append(image, archive...), true, 0x400},
+		{"zeros", coff, false, 0},
+		{"bad signature", append(notPE, archive...), false, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			arch := NewArchive(bytes.NewReader(test.data), int64(len(test.data)), test.name)
+			if err := arch.CheckFile(); err != nil {
+				t.Fatal(err)
+			}
+			info := arch.PE()
+			if (info != nil) != test.isPE {
+				t.Fatalf("PE() = %+v, want a PE: %v", info, test.isPE)
+			}
+			if info != nil && info.OverlayOffset != test.overlay {
+				t.Errorf("overlay at %d, want %d", info.OverlayOffset, test.overlay)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package pyinstaller
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	pycMagic27  = "\x03\xf3\r\n"
+	pycMagic311 = "\xa7\r\r\n"
+)
+
+func TestExtractPYZ(t *testing.T) {
+	modules := []testModule{
+		{"foo", PYZ_ITEM_MODULE, []byte("foo code")},
+		{"pkg", PYZ_ITEM_PKG, []byte("pkg code")},
+		{"pkg.sub", PYZ_ITEM_MODULE, []byte("sub code")},
+	}
+	pyc311 := func(code string) string {
+		return pycMagic311 + "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00" + code
+	}
+	want311 := map[string]string{
+		"PYZ.pyz_extracted/foo.pyc":          pyc311("foo code"),
+		"PYZ.pyz_extracted/pkg/__init__.pyc": pyc311("pkg code"),
+		"PYZ.pyz_extracted/pkg/sub.pyc":      pyc311("sub code"),
+	}
+
+	tests := []struct {
+		name          string
+		pythonVersion int
+		pyz           []byte
+		want          map[string]string
+	}{
+		// The positions of Python 2 tables of contents are TYPE_INT, their
+		// names byte strings, and pycs have an 8 byte header
+		{"python 2", 27, buildPYZ(pycMagic27, modules, false, true), map[string]string{
+			"PYZ.pyz_extracted/foo.pyc":          pycMagic27 + "\x00\x00\x00\x00foo code",
+			"PYZ.pyz_extracted/pkg/__init__.pyc": pycMagic27 + "\x00\x00\x00\x00pkg code",
+			"PYZ.pyz_extracted/pkg/sub.pyc":      pycMagic27 + "\x00\x00\x00\x00sub code",
+		}},
+		{"python 2 dict", 27, buildPYZ(pycMagic27, modules[:1], true, true), map[string]string{
+			"PYZ.pyz_extracted/foo.pyc": pycMagic27 + "\x00\x00\x00\x00foo code",
+		}},
+		{"dict", 311, buildPYZ(pycMagic311, modules, true, false), want311},
+		{"list", 311, buildPYZ(pycMagic311, modules, false, false), want311},
+		// Data files are written as is, namespace packages as directories
+		{"pyinstaller 6", 311, buildPYZ(pycMagic311, []testModule{
+			{"foo", PYZ_ITEM_MODULE, []byte("foo code")},
+			{"pkg/data.txt", PYZ_ITEM_DATA, []byte("data")},
+			{"nspkg", PYZ_ITEM_NSPKG, nil},
+		}, false, false), map[string]string{
+			"PYZ.pyz_extracted/foo.pyc":      pyc311("foo code"),
+			"PYZ.pyz_extracted/pkg/data.txt": "data",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			arch := openArchive(t, buildArchiveFor([]testEntry{{"PYZ.pyz", 'z', test.pyz, false}}, 21, test.pythonVersion))
+			out := NewMemSink()
+			if err := arch.ExtractFiles(out); err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, name := range out.Names() {
+				if name != "PYZ.pyz" {
+					data, _ := out.File(name)
+					got[name] = string(data)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("extracted %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	// Namespace packages only show up in sinks holding directories
+	root := t.TempDir()
+	out, err := NewDirSink(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pyz := buildPYZ(pycMagic311, []testModule{{"ns.pkg", PYZ_ITEM_NSPKG, nil}}, false, false)
+	if err := openArchive(t, buildArchive([]testEntry{{"PYZ.pyz", 'z', pyz, false}})).ExtractFiles(out); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filepath.Join(root, "PYZ.pyz_extracted", "ns", "pkg")); err != nil || !info.IsDir() {
+		t.Errorf("namespace package directory: %v", err)
+	}
+}
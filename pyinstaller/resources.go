@@ -0,0 +1,435 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"unicode/utf16"
+)
+
+// Resource types
+const (
+	RT_ICON       = 3
+	RT_GROUP_ICON = 14
+	RT_VERSION    = 16
+	RT_MANIFEST   = 24
+)
+
+// PE_RESOURCES_DIR is where ExtractFiles writes the resources of PE inputs
+const PE_RESOURCES_DIR = "pe_resources"
+
+var ErrInvalidResources = errors.New("invalid resource directory")
+
+// Limits of the resource directory walk, far above what resource compilers
+// produce
+const (
+	maxResourceEntries = 65536
+	maxResources       = 4096
+	maxIconsSize       = 64 << 20
+)
+
+// PEResources are the resources of a PE file useful for attribution.
+type PEResources struct {
+	// Version is nil without a VS_VERSIONINFO resource
+	Version *VersionInfo
+	// Manifest is the application manifest, an XML document
+	Manifest []byte
+	// ExecutionLevel is the requestedExecutionLevel of the manifest, which
+	// is requireAdministrator for --uac-admin builds
+	ExecutionLevel string
+	Icons          []PEIcon
+}
+
+// PEIcon is an icon group, converted to the .ico format.
+type PEIcon struct {
+	Name string
+	Data []byte
+}
+
+// VersionInfo is a decoded VS_VERSIONINFO resource.
+type VersionInfo struct {
+	FileVersion    string
+	ProductVersion string
+	FileFlags      uint32
+	FileOS         uint32
+	FileType       uint32
+	// Strings maps the language and code page of each string table, such
+	// as 040904b0, to its strings
+	Strings      map[string]map[string]string `json:",omitempty"`
+	Translations []string                     `json:",omitempty"`
+}
+
+// peResource is a leaf of the resource directory
+type peResource struct {
+	typ  uint32
+	name string
+	id   uint32
+	data []byte
+}
+
+// readPEResources walks the type, name and language levels of the resource
+// directory
+func readPEResources(f *pe.File) ([]peResource, error) {
+	var dirs []pe.DataDirectory
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+	case *pe.OptionalHeader64:
+		dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+	}
+	if len(dirs) <= pe.IMAGE_DIRECTORY_ENTRY_RESOURCE || dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE].Size == 0 {
+		return nil, nil
+	}
+
+	sectionData := make(map[*pe.Section][]byte)
+	// rvaData returns the size bytes at rva, or the rest of the section if
+	// size is -1
+	rvaData := func(rva uint32, size int64) ([]byte, error) {
+		for _, s := range f.Sections {
+			if rva < s.VirtualAddress || rva >= s.VirtualAddress+max(s.VirtualSize, s.Size) {
+				continue
+			}
+			data, ok := sectionData[s]
+			if !ok {
+				var err error
+				if data, err = s.Data(); err != nil {
+					return nil, err
+				}
+				sectionData[s] = data
+			}
+			// The virtual size may exceed the data stored in the file
+			start := int64(rva - s.VirtualAddress)
+			if start > int64(len(data)) {
+				return nil, ErrInvalidResources
+			}
+			if size == -1 {
+				size = int64(len(data)) - start
+			}
+			if size < 0 || start+size > int64(len(data)) {
+				return nil, ErrInvalidResources
+			}
+			return data[start : start+size], nil
+		}
+		return nil, ErrInvalidResources
+	}
+
+	root, err := rvaData(dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE].VirtualAddress, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []peResource
+	// Directories may be shared between several parents or lie anywhere in
+	// the section, each is only walked once, which also stops loops. The
+	// number of entries is bounded as well.
+	visited := make(map[uint32]bool)
+	entries := 0
+	var walk func(offset uint32, level int, res peResource) error
+	walk = func(offset uint32, level int, res peResource) error {
+		if level > 2 || int64(offset)+16 > int64(len(root)) {
+			return ErrInvalidResources
+		}
+		if visited[offset] {
+			return nil
+		}
+		visited[offset] = true
+		count := int(binary.LittleEndian.Uint16(root[offset+12:])) + int(binary.LittleEndian.Uint16(root[offset+14:]))
+		for i := 0; i < count; i++ {
+			if entries++; entries > maxResourceEntries || len(resources) >= maxResources {
+				return fmt.Errorf("%w: too many entries", ErrInvalidResources)
+			}
+			entry := int64(offset) + 16 + 8*int64(i)
+			if entry+8 > int64(len(root)) {
+				return ErrInvalidResources
+			}
+			nameField := binary.LittleEndian.Uint32(root[entry:])
+			dataField := binary.LittleEndian.Uint32(root[entry+4:])
+
+			child := res
+			switch level {
+			case 0:
+				child.typ = nameField
+			case 1:
+				if nameField&0x80000000 != 0 {
+					child.name = resourceString(root, nameField&0x7fffffff)
+				} else {
+					child.id = nameField
+					child.name = strconv.Itoa(int(nameField))
+				}
+			}
+
+			if dataField&0x80000000 != 0 {
+				if err := walk(dataField&0x7fffffff, level+1, child); err != nil {
+					return err
+				}
+				continue
+			}
+			// IMAGE_RESOURCE_DATA_ENTRY: rva, size, code page, reserved
+			if int64(dataField)+16 > int64(len(root)) {
+				return ErrInvalidResources
+			}
+			rva := binary.LittleEndian.Uint32(root[dataField:])
+			size := binary.LittleEndian.Uint32(root[dataField+4:])
+			data, err := rvaData(rva, int64(size))
+			if err != nil {
+				return err
+			}
+			child.data = data
+			resources = append(resources, child)
+		}
+		return nil
+	}
+	if err := walk(0, 0, peResource{}); err != nil {
+		return resources, err
+	}
+	return resources, nil
+}
+
+// resourceString reads an IMAGE_RESOURCE_DIR_STRING_U, a length prefixed
+// UTF-16 string
+func resourceString(root []byte, offset uint32) string {
+	if int64(offset)+2 > int64(len(root)) {
+		return ""
+	}
+	start := int(offset) + 2
+	length := int(binary.LittleEndian.Uint16(root[offset:]))
+	return utf16String(root[start:min(start+2*length, len(root))])
+}
+
+func utf16String(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// parsePEResources extracts the version info, manifest and icons
+func parsePEResources(resources []peResource) *PEResources {
+	res := &PEResources{}
+	iconsLimit := maxIconsSize
+	icons := make(map[uint32][]byte)
+	for _, r := range resources {
+		if r.typ == RT_ICON {
+			icons[r.id] = r.data
+		}
+	}
+	for _, r := range resources {
+		switch r.typ {
+		case RT_VERSION:
+			if res.Version == nil {
+				res.Version, _ = ParseVersionInfo(r.data)
+			}
+		case RT_MANIFEST:
+			if res.Manifest == nil {
+				res.Manifest = r.data
+				res.ExecutionLevel = executionLevel(r.data)
+			}
+		case RT_GROUP_ICON:
+			// Groups may share images, the total size is bounded
+			if ico := iconFile(r.data, icons, iconsLimit); ico != nil {
+				iconsLimit -= len(ico)
+				res.Icons = append(res.Icons, PEIcon{r.name, ico})
+			}
+		}
+	}
+	return res
+}
+
+// iconFile builds an .ico file from a GRPICONDIR and its RT_ICON images,
+// or returns nil if it would exceed limit bytes
+func iconFile(group []byte, icons map[uint32][]byte, limit int) []byte {
+	if len(group) < 6 {
+		return nil
+	}
+	count := int(binary.LittleEndian.Uint16(group[4:]))
+	if 6+14*count > len(group) {
+		return nil
+	}
+
+	// Images missing from the file, or listed twice, are left out
+	var entries [][]byte
+	seen := make(map[uint32]bool)
+	size := 6
+	for i := 0; i < count; i++ {
+		entry := group[6+14*i : 6+14*i+14]
+		id := uint32(binary.LittleEndian.Uint16(entry[12:]))
+		if image, ok := icons[id]; ok && !seen[id] {
+			seen[id] = true
+			entries = append(entries, entry)
+			size += 16 + len(image)
+		}
+	}
+	if len(entries) == 0 || size > limit {
+		return nil
+	}
+
+	// ICONDIR, then an ICONDIRENTRY per image: the GRPICONDIRENTRY with the
+	// image offset in place of the resource id
+	ico := make([]byte, 0, size)
+	ico = append(ico, group[:4]...)
+	ico = binary.LittleEndian.AppendUint16(ico, uint16(len(entries)))
+	offset := 6 + 16*len(entries)
+	for _, entry := range entries {
+		image := icons[uint32(binary.LittleEndian.Uint16(entry[12:]))]
+		ico = append(ico, entry[:8]...)
+		ico = binary.LittleEndian.AppendUint32(ico, uint32(len(image)))
+		ico = binary.LittleEndian.AppendUint32(ico, uint32(offset))
+		offset += len(image)
+	}
+	for _, entry := range entries {
+		ico = append(ico, icons[uint32(binary.LittleEndian.Uint16(entry[12:]))]...)
+	}
+	return ico
+}
+
+// executionLevel returns the level requested by a manifest
+func executionLevel(manifest []byte) string {
+	d := xml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return ""
+		}
+		if el, ok := tok.(xml.StartElement); ok && el.Name.Local == "requestedExecutionLevel" {
+			for _, attr := range el.Attr {
+				if attr.Name.Local == "level" {
+					return attr.Value
+				}
+			}
+		}
+	}
+}
+
+// versionBlock is a node of a VS_VERSIONINFO resource: a key, a value and
+// children
+type versionBlock struct {
+	key      string
+	value    []byte
+	text     bool
+	children []versionBlock
+}
+
+// parseVersionBlock decodes the block at the start of data, returning it and
+// its length
+func parseVersionBlock(data []byte) (versionBlock, int, error) {
+	var b versionBlock
+	if len(data) < 6 {
+		return b, 0, ErrInvalidResources
+	}
+	length := int(binary.LittleEndian.Uint16(data))
+	valueLength := int(binary.LittleEndian.Uint16(data[2:]))
+	b.text = binary.LittleEndian.Uint16(data[4:]) == 1
+	if length < 6 || length > len(data) {
+		return b, 0, ErrInvalidResources
+	}
+	data = data[:length]
+
+	pos := 6
+	for pos+1 < len(data) && (data[pos] != 0 || data[pos+1] != 0) {
+		pos += 2
+	}
+	b.key = utf16String(data[6:pos])
+	pos = align4(pos + 2)
+
+	// Text values are measured in characters
+	if b.text {
+		valueLength *= 2
+	}
+	if pos < len(data) {
+		b.value = data[pos:min(pos+valueLength, len(data))]
+	}
+	pos = align4(pos + valueLength)
+
+	for pos < len(data) {
+		child, childLength, err := parseVersionBlock(data[pos:])
+		if err != nil {
+			return b, length, err
+		}
+		b.children = append(b.children, child)
+		pos = align4(pos + childLength)
+	}
+	return b, length, nil
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// ParseVersionInfo decodes a VS_VERSIONINFO resource.
+func ParseVersionInfo(data []byte) (*VersionInfo, error) {
+	root, _, err := parseVersionBlock(data)
+	if err != nil {
+		return nil, err
+	}
+	if root.key != "VS_VERSION_INFO" {
+		return nil, fmt.Errorf("%w: unexpected version info key %q", ErrInvalidResources, root.key)
+	}
+
+	info := &VersionInfo{}
+	// VS_FIXEDFILEINFO
+	if v := root.value; len(v) >= 52 && binary.LittleEndian.Uint32(v) == 0xfeef04bd {
+		version := func(ms, ls uint32) string {
+			return fmt.Sprintf("%d.%d.%d.%d", ms>>16, ms&0xffff, ls>>16, ls&0xffff)
+		}
+		u32 := func(i int) uint32 { return binary.LittleEndian.Uint32(v[4*i:]) }
+		info.FileVersion = version(u32(2), u32(3))
+		info.ProductVersion = version(u32(4), u32(5))
+		info.FileFlags = u32(7) & u32(6)
+		info.FileOS = u32(8)
+		info.FileType = u32(9)
+	}
+
+	for _, child := range root.children {
+		switch child.key {
+		case "StringFileInfo":
+			for _, table := range child.children {
+				strings := make(map[string]string)
+				for _, s := range table.children {
+					strings[s.key] = string(bytes.TrimRight([]byte(utf16String(s.value)), "\x00"))
+				}
+				if info.Strings == nil {
+					info.Strings = make(map[string]map[string]string)
+				}
+				info.Strings[table.key] = strings
+			}
+		case "VarFileInfo":
+			for _, v := range child.children {
+				if v.key != "Translation" {
+					continue
+				}
+				for i := 0; i+4 <= len(v.value); i += 4 {
+					info.Translations = append(info.Translations, fmt.Sprintf("%04x%04x",
+						binary.LittleEndian.Uint16(v.value[i:]), binary.LittleEndian.Uint16(v.value[i+2:])))
+				}
+			}
+		}
+	}
+	return info, nil
+}
+
+// writePEResources writes the resources of a PE input to PE_RESOURCES_DIR
+func (p *Archive) writePEResources() {
+	if p.pe == nil || p.pe.Resources == nil {
+		return
+	}
+	res := p.pe.Resources
+	if res.Version != nil {
+		if data, err := json.MarshalIndent(res.Version, "", "  "); err == nil {
+			p.writeRawData(p.ensureUnique(path.Join(PE_RESOURCES_DIR, "version_info"), ".json")+".json", append(data, '\n'))
+		}
+	}
+	if res.Manifest != nil {
+		p.writeRawData(p.ensureUnique(path.Join(PE_RESOURCES_DIR, "manifest"), ".xml")+".xml", res.Manifest)
+	}
+	for _, icon := range res.Icons {
+		name := path.Join(PE_RESOURCES_DIR, "icon_"+sanitizePath(icon.Name))
+		p.writeRawData(p.ensureUnique(name, ".ico")+".ico", icon.Data)
+	}
+}
@@ -0,0 +1,240 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+// testResource is a resource for buildResources
+type testResource struct {
+	typ, id uint32
+	data    []byte
+}
+
+func putResourceDir(b []byte, count int) {
+	binary.LittleEndian.PutUint16(b[14:], uint16(count))
+}
+
+func putResourceEntry(b []byte, name, data uint32) {
+	binary.LittleEndian.PutUint32(b, name)
+	binary.LittleEndian.PutUint32(b[4:], data)
+}
+
+// buildResources lays out a resource directory at RVA va, giving every
+// resource its own name and language directories
+func buildResources(va uint32, resources []testResource) []byte {
+	const dirSize = 16 + 8
+	n := len(resources)
+	b := make([]byte, 16+8*n+n*(2*dirSize+16))
+	putResourceDir(b, n)
+	for i, r := range resources {
+		sub := 16 + 8*n + i*(2*dirSize+16)
+		putResourceEntry(b[16+8*i:], r.typ, 0x80000000|uint32(sub))
+		putResourceDir(b[sub:], 1)
+		putResourceEntry(b[sub+16:], r.id, 0x80000000|uint32(sub+dirSize))
+		putResourceDir(b[sub+dirSize:], 1)
+		putResourceEntry(b[sub+dirSize+16:], 0x409, uint32(sub+2*dirSize))
+		binary.LittleEndian.PutUint32(b[sub+2*dirSize:], va+uint32(len(b)))
+		binary.LittleEndian.PutUint32(b[sub+2*dirSize+4:], uint32(len(r.data)))
+		b = append(b, r.data...)
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+	}
+	return b
+}
+
+// versionNode encodes a VS_VERSIONINFO block
+func versionNode(key string, value []byte, text bool, children ...[]byte) []byte {
+	b := make([]byte, 6)
+	for _, c := range utf16.Encode([]rune(key + "\x00")) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	valueLength := len(value)
+	if text {
+		valueLength /= 2
+		binary.LittleEndian.PutUint16(b[4:], 1)
+	}
+	binary.LittleEndian.PutUint16(b[2:], uint16(valueLength))
+	b = append(b, value...)
+	for _, child := range children {
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+		b = append(b, child...)
+	}
+	binary.LittleEndian.PutUint16(b, uint16(len(b)))
+	return b
+}
+
+func utf16z(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s + "\x00")) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+func readTestResources(t *testing.T, data []byte) ([]peResource, error) {
+	t.Helper()
+	f, err := pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("pe.NewFile: %v", err)
+	}
+	return readPEResources(f)
+}
+
+func TestPEResources(t *testing.T) {
+	fixed := make([]byte, 52)
+	for i, v := range []uint32{0xfeef04bd, 0x10000, 0x10002, 0x30004, 0x10002, 0x30004, 0x3f, 0, 0x40004, 1} {
+		binary.LittleEndian.PutUint32(fixed[4*i:], v)
+	}
+	version := versionNode("VS_VERSION_INFO", fixed, false,
+		versionNode("StringFileInfo", nil, false,
+			versionNode("040904b0", nil, false,
+				versionNode("CompanyName", utf16z("Example Corp"), true),
+				versionNode("ProductName", utf16z("Example"), true))),
+		versionNode("VarFileInfo", nil, false,
+			versionNode("Translation", []byte{0x09, 0x04, 0xb0, 0x04}, false)))
+	manifest := []byte(`<assembly xmlns="urn:schemas-microsoft-com:asm.v1"><trustInfo xmlns="urn:schemas-microsoft-com:asm.v3"><security><requestedPrivileges><requestedExecutionLevel level="requireAdministrator" uiAccess="false"/></requestedPrivileges></security></trustInfo></assembly>`)
+	image := []byte("\x89PNG fake image")
+	group := []byte{0, 0, 1, 0, 2, 0,
+		32, 32, 0, 0, 1, 0, 32, 0, byte(len(image)), 0, 0, 0, 7, 0,
+		32, 32, 0, 0, 1, 0, 32, 0, byte(len(image)), 0, 0, 0, 7, 0}
+
+	section := buildResources(0x1000, []testResource{
+		{RT_ICON, 7, image},
+		{RT_GROUP_ICON, 1, group},
+		{RT_VERSION, 1, version},
+		{RT_MANIFEST, 1, manifest},
+	})
+	resources, err := readTestResources(t, buildPE(section, 0, 0x1000, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := parsePEResources(resources)
+
+	if res.ExecutionLevel != "requireAdministrator" || !bytes.Equal(res.Manifest, manifest) {
+		t.Errorf("manifest %q, execution level %q", res.Manifest, res.ExecutionLevel)
+	}
+	v := res.Version
+	if v == nil {
+		t.Fatal("no version info")
+	}
+	if v.FileVersion != "1.2.3.4" || v.ProductVersion != "1.2.3.4" || v.FileOS != 0x40004 || v.FileType != 1 {
+		t.Errorf("fixed file info %+v", v)
+	}
+	if s := v.Strings["040904b0"]; s["CompanyName"] != "Example Corp" || s["ProductName"] != "Example" {
+		t.Errorf("strings %v", v.Strings)
+	}
+	if len(v.Translations) != 1 || v.Translations[0] != "040904b0" {
+		t.Errorf("translations %v", v.Translations)
+	}
+
+	// The image listed twice is written once
+	want := append([]byte{0, 0, 1, 0, 1, 0, 32, 32, 0, 0, 1, 0, 32, 0, byte(len(image)), 0, 0, 0, 22, 0, 0, 0}, image...)
+	if len(res.Icons) != 1 || res.Icons[0].Name != "1" || !bytes.Equal(res.Icons[0].Data, want) {
+		t.Errorf("icons %q, want %q", res.Icons, want)
+	}
+}
+
+func TestPEResourcesBackwardDirectories(t *testing.T) {
+	// The language directory and data entry come before the name directory
+	// pointing to them
+	manifest := []byte("<assembly/>")
+	section := make([]byte, 88, 88+len(manifest))
+	putResourceDir(section, 1)
+	putResourceEntry(section[16:], RT_MANIFEST, 0x80000000|64)
+	putResourceDir(section[24:], 1)
+	putResourceEntry(section[40:], 0x409, 48)
+	binary.LittleEndian.PutUint32(section[48:], 0x1000+88)
+	binary.LittleEndian.PutUint32(section[52:], uint32(len(manifest)))
+	putResourceDir(section[64:], 1)
+	putResourceEntry(section[80:], 1, 0x80000000|24)
+	section = append(section, manifest...)
+
+	resources, err := readTestResources(t, buildPE(section, 0, 0x1000, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := parsePEResources(resources); !bytes.Equal(res.Manifest, manifest) {
+		t.Errorf("manifest %q, want %q", res.Manifest, manifest)
+	}
+}
+
+// sharedResourceDirs returns a resource section whose root has n entries
+// pointing to the same directory, whose entries all point to a third one
+func sharedResourceDirs(n int) []byte {
+	dirSize := 16 + 8*n
+	section := make([]byte, 3*dirSize+16+4)
+	for level := 0; level < 3; level++ {
+		dir := section[level*dirSize:]
+		putResourceDir(dir, n)
+		for i := 0; i < n; i++ {
+			target := 0x80000000 | uint32((level+1)*dirSize)
+			if level == 2 {
+				target = uint32(3 * dirSize)
+			}
+			putResourceEntry(dir[16+8*i:], uint32(i), target)
+		}
+	}
+	binary.LittleEndian.PutUint32(section[3*dirSize:], 0x1000+uint32(3*dirSize+16))
+	binary.LittleEndian.PutUint32(section[3*dirSize+4:], 4)
+	return section
+}
+
+func TestPEResourcesMalformed(t *testing.T) {
+	// The resource directory lies in the part of the section which isn't
+	// stored in the file
+	t.Run("virtual tail", func(t *testing.T) {
+		data := buildPE(make([]byte, 0x200), 0x1000, 0x1800, nil)
+		if _, err := readTestResources(t, data); !errors.Is(err, ErrInvalidResources) {
+			t.Errorf("err = %v, want ErrInvalidResources", err)
+		}
+		arch := NewArchive(bytes.NewReader(data), int64(len(data)), "tail.exe")
+		if err := arch.CheckFile(); !errors.Is(err, ErrMissingCookie) {
+			t.Errorf("CheckFile: %v, want ErrMissingCookie", err)
+		}
+	})
+
+	t.Run("shared directories", func(t *testing.T) {
+		const n = 3000
+		resources, err := readTestResources(t, buildPE(sharedResourceDirs(n), 0, 0x1000, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resources) != n {
+			t.Errorf("found %d resources, want %d", len(resources), n)
+		}
+	})
+
+	// Distinct name directories under the root, sharing a language
+	// directory
+	t.Run("too many entries", func(t *testing.T) {
+		const n = 40000
+		names := 16 + 8*n
+		lang := names + 24*n
+		section := make([]byte, lang+24+16+4)
+		putResourceDir(section, n)
+		for i := 0; i < n; i++ {
+			putResourceEntry(section[16+8*i:], RT_MANIFEST, 0x80000000|uint32(names+24*i))
+			putResourceDir(section[names+24*i:], 1)
+			putResourceEntry(section[names+24*i+16:], uint32(i), 0x80000000|uint32(lang))
+		}
+		putResourceDir(section[lang:], 1)
+		putResourceEntry(section[lang+16:], 0x409, uint32(lang+24))
+		binary.LittleEndian.PutUint32(section[lang+24:], 0x1000+uint32(lang+40))
+		binary.LittleEndian.PutUint32(section[lang+28:], 4)
+
+		if _, err := readTestResources(t, buildPE(section, 0, 0x1000, nil)); !errors.Is(err, ErrInvalidResources) {
+			t.Errorf("err = %v, want ErrInvalidResources", err)
+		}
+	})
+}
@@ -0,0 +1,352 @@
+package pyinstaller
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink receives the files produced by an extraction. Names are relative,
+// slash separated paths which have already been sanitized.
+type Sink interface {
+	WriteFile(name string, data []byte) error
+	Exists(name string) bool
+}
+
+// DirMaker is implemented by sinks which can hold empty directories, such as
+// namespace packages.
+type DirMaker interface {
+	MkdirAll(name string) error
+}
+
+// Symlinker is implemented by sinks which can hold symbolic links. target is
+// relative to the directory of the link name.
+type Symlinker interface {
+	Symlink(target, name string) error
+}
+
+// Opener is implemented by sinks which can read back the files written to
+// them. The returned reader is closed by the caller if it is an io.Closer.
+type Opener interface {
+	Open(name string) (io.ReaderAt, int64, error)
+}
+
+// DirSink writes files below a directory on disk. It refuses to write
+// through symbolic links below Root, which could point outside of it.
+type DirSink struct {
+	Root string
+}
+
+func NewDirSink(root string) (*DirSink, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &DirSink{Root: root}, nil
+}
+
+// checkPath fails if name goes through a symbolic link or leaves Root
+func (s *DirSink) checkPath(name string) error {
+	fullPath := s.Root
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return fmt.Errorf("%w: %s", ErrUnsafePath, name)
+		}
+		fullPath = filepath.Join(fullPath, part)
+		info, err := os.Lstat(fullPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %s", ErrUnsafePath, name)
+		}
+	}
+	return nil
+}
+
+func (s *DirSink) WriteFile(name string, data []byte) error {
+	if err := s.checkPath(name); err != nil {
+		return err
+	}
+	fullPath := filepath.Join(s.Root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(fullPath, data, 0666)
+}
+
+func (s *DirSink) MkdirAll(name string) error {
+	if err := s.checkPath(name); err != nil {
+		return err
+	}
+	return os.MkdirAll(filepath.Join(s.Root, filepath.FromSlash(name)), 0755)
+}
+
+func (s *DirSink) Symlink(target, name string) error {
+	if err := s.checkPath(name); err != nil {
+		return err
+	}
+	fullPath := filepath.Join(s.Root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	return os.Symlink(filepath.FromSlash(target), fullPath)
+}
+
+func (s *DirSink) Open(name string) (io.ReaderAt, int64, error) {
+	if err := s.checkPath(name); err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(filepath.Join(s.Root, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+func (s *DirSink) Exists(name string) bool {
+	_, err := os.Lstat(filepath.Join(s.Root, filepath.FromSlash(name)))
+	return err == nil
+}
+
+// ZipSink writes files into a zip archive. The caller owns the zip.Writer
+// and must close it once extraction is done.
+type ZipSink struct {
+	w       *zip.Writer
+	written map[string]bool
+}
+
+func NewZipSink(w *zip.Writer) *ZipSink {
+	return &ZipSink{w: w, written: make(map[string]bool)}
+}
+
+func (s *ZipSink) WriteFile(name string, data []byte) error {
+	f, err := s.w.CreateHeader(&zip.FileHeader{
+		Name:   name,
+		Method: zip.Store,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	s.written[name] = true
+	return s.w.Flush()
+}
+
+func (s *ZipSink) MkdirAll(name string) error {
+	if s.written[name+"/"] {
+		return nil
+	}
+	if _, err := s.w.Create(name + "/"); err != nil {
+		return err
+	}
+	s.written[name+"/"] = true
+	return s.w.Flush()
+}
+
+func (s *ZipSink) Symlink(target, name string) error {
+	fh := &zip.FileHeader{Name: name, Method: zip.Store}
+	fh.SetMode(os.ModeSymlink | 0777)
+	f, err := s.w.CreateHeader(fh)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write([]byte(target)); err != nil {
+		return err
+	}
+	s.written[name] = true
+	return s.w.Flush()
+}
+
+func (s *ZipSink) Exists(name string) bool {
+	return s.written[name]
+}
+
+// TarSink writes files into a tar archive. The caller owns the tar.Writer
+// and must close it once extraction is done.
+type TarSink struct {
+	w       *tar.Writer
+	written map[string]bool
+	dirs    map[string]bool
+}
+
+func NewTarSink(w *tar.Writer) *TarSink {
+	return &TarSink{w: w, written: make(map[string]bool), dirs: make(map[string]bool)}
+}
+
+func (s *TarSink) mkdirAll(dir string) error {
+	if dir == "." || dir == "/" || s.dirs[dir] {
+		return nil
+	}
+	if err := s.mkdirAll(path.Dir(dir)); err != nil {
+		return err
+	}
+	s.dirs[dir] = true
+	return s.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     dir + "/",
+		Mode:     0755,
+		ModTime:  time.Now(),
+	})
+}
+
+func (s *TarSink) WriteFile(name string, data []byte) error {
+	if err := s.mkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
+	if err := s.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+	}); err != nil {
+		return err
+	}
+	if _, err := s.w.Write(data); err != nil {
+		return err
+	}
+	s.written[name] = true
+	return nil
+}
+
+func (s *TarSink) MkdirAll(name string) error {
+	return s.mkdirAll(name)
+}
+
+func (s *TarSink) Symlink(target, name string) error {
+	if err := s.mkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
+	if err := s.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeSymlink,
+		Name:     name,
+		Linkname: target,
+		Mode:     0777,
+		ModTime:  time.Now(),
+	}); err != nil {
+		return err
+	}
+	s.written[name] = true
+	return nil
+}
+
+func (s *TarSink) Exists(name string) bool {
+	return s.written[name]
+}
+
+// prefixSink writes into a directory of another sink
+type prefixSink struct {
+	Sink
+	prefix string
+}
+
+func (s *prefixSink) WriteFile(name string, data []byte) error {
+	return s.Sink.WriteFile(s.prefix+name, data)
+}
+
+func (s *prefixSink) Exists(name string) bool {
+	return s.Sink.Exists(s.prefix + name)
+}
+
+// canOpen reports whether files written to s can be read back
+func canOpen(s Sink) bool {
+	if ps, ok := s.(*prefixSink); ok {
+		return canOpen(ps.Sink)
+	}
+	_, ok := s.(Opener)
+	return ok
+}
+
+func (s *prefixSink) Open(name string) (io.ReaderAt, int64, error) {
+	if o, ok := s.Sink.(Opener); ok {
+		return o.Open(s.prefix + name)
+	}
+	return nil, 0, errors.ErrUnsupported
+}
+
+func (s *prefixSink) MkdirAll(name string) error {
+	if d, ok := s.Sink.(DirMaker); ok {
+		return d.MkdirAll(s.prefix + name)
+	}
+	return errors.ErrUnsupported
+}
+
+func (s *prefixSink) Symlink(target, name string) error {
+	if l, ok := s.Sink.(Symlinker); ok {
+		return l.Symlink(target, s.prefix+name)
+	}
+	return errors.ErrUnsupported
+}
+
+// MemSink keeps extracted files in memory.
+type MemSink struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func NewMemSink() *MemSink {
+	return &MemSink{files: make(map[string][]byte)}
+}
+
+func (s *MemSink) WriteFile(name string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[name] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemSink) Exists(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.files[name]
+	return ok
+}
+
+func (s *MemSink) Open(name string) (io.ReaderAt, int64, error) {
+	data, ok := s.File(name)
+	if !ok {
+		return nil, 0, fs.ErrNotExist
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
+// File returns the contents of an extracted file.
+func (s *MemSink) File(name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.files[name]
+	return data, ok
+}
+
+// Names returns the names of all extracted files in sorted order.
+func (s *MemSink) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.files))
+	for name := range s.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
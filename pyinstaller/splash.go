@@ -0,0 +1,160 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/png"
+	"path"
+	"strings"
+)
+
+// Splash resource headers, all big endian. The library names are followed by
+// six uint32: script_len, script_offset, image_len, image_offset,
+// requirements_len and requirements_offset.
+const (
+	SPLASH5_HEADER_SIZE = 4*16 + 6*4         // tcl_libname[16] tk_libname[16] tk_lib[16] rundir[16], PyInstaller 4.x - 5.x
+	SPLASH6_HEADER_SIZE = 32 + 32 + 16 + 6*4 // tcl_libname[32] tk_libname[32] tk_lib[16], PyInstaller 6
+)
+
+// SplashScreen is the ARCHIVE_ITEM_SPLASH entry of archives built with
+// --splash.
+type SplashScreen struct {
+	TclLibName string
+	TkLibName  string
+	TkLib      string
+	// RunDir is only present before PyInstaller 6
+	RunDir string
+
+	Script string
+	Image  []byte
+	// ImageFormat is "png" or "gif", or empty if the image couldn't be
+	// decoded
+	ImageFormat string
+	ImageWidth  int
+	ImageHeight int
+
+	// Requirements are the Tcl/Tk files extracted before showing the splash
+	Requirements []string
+}
+
+// Splash returns the splash screen met by ExtractFiles, or nil.
+func (p *Archive) Splash() *SplashScreen {
+	return p.splash
+}
+
+// ParseSplash decodes the resources of an ARCHIVE_ITEM_SPLASH entry.
+func ParseSplash(data []byte) (*SplashScreen, error) {
+	for _, headerSize := range []int{SPLASH6_HEADER_SIZE, SPLASH5_HEADER_SIZE} {
+		if len(data) < headerSize {
+			continue
+		}
+		var fields [6]uint32
+		for i := range fields {
+			fields[i] = binary.BigEndian.Uint32(data[headerSize-24+4*i:])
+		}
+		sections, ok := splashSections(data, headerSize, fields)
+		if !ok {
+			continue
+		}
+
+		s := &SplashScreen{Script: string(sections[0]), Image: sections[1]}
+		cString := func(b []byte) string {
+			return string(bytes.TrimRight(b, "\x00"))
+		}
+		if headerSize == SPLASH6_HEADER_SIZE {
+			s.TclLibName = cString(data[0:32])
+			s.TkLibName = cString(data[32:64])
+			s.TkLib = cString(data[64:80])
+		} else {
+			s.TclLibName = cString(data[0:16])
+			s.TkLibName = cString(data[16:32])
+			s.TkLib = cString(data[32:48])
+			s.RunDir = cString(data[48:64])
+		}
+		for _, name := range strings.Split(string(sections[2]), "\x00") {
+			if name != "" {
+				s.Requirements = append(s.Requirements, name)
+			}
+		}
+		if config, format, err := image.DecodeConfig(bytes.NewReader(s.Image)); err == nil {
+			s.ImageFormat = format
+			s.ImageWidth, s.ImageHeight = config.Width, config.Height
+		}
+		return s, nil
+	}
+	return nil, ErrInvalidSplash
+}
+
+// splashSections returns the script, image and requirements sections if the
+// header fields describe disjoint ranges after the header which add up to
+// the data
+func splashSections(data []byte, headerSize int, fields [6]uint32) ([3][]byte, bool) {
+	var sections [3][]byte
+	total := headerSize
+	for i := range sections {
+		length, offset := int64(fields[2*i]), int64(fields[2*i+1])
+		if offset < int64(headerSize) || offset+length > int64(len(data)) {
+			return sections, false
+		}
+		for j := 0; j < i; j++ {
+			otherLength, otherOffset := int64(fields[2*j]), int64(fields[2*j+1])
+			if offset < otherOffset+otherLength && otherOffset < offset+length {
+				return sections, false
+			}
+		}
+		sections[i] = data[offset : offset+length]
+		total += int(length)
+	}
+	return sections, total == len(data)
+}
+
+// PNG converts the splash image to PNG. Tk reads GIF as well, which
+// PyInstaller bundles as is.
+func (s *SplashScreen) PNG() ([]byte, error) {
+	switch s.ImageFormat {
+	case "png":
+		return s.Image, nil
+	case "gif":
+		img, err := gif.Decode(bytes.NewReader(s.Image))
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported image format %q", s.ImageFormat)
+}
+
+// extractSplash writes the image and script of a splash entry to
+// <entryPath>_extracted and reports its metadata.
+func (p *Archive) extractSplash(entryPath string, data []byte) error {
+	s, err := ParseSplash(data)
+	if err != nil {
+		return err
+	}
+	p.splash = s
+	dirName := entryPath + "_extracted"
+
+	if s.ImageFormat != "" {
+		p.logf("[+] Splash screen: %dx%d %s image", s.ImageWidth, s.ImageHeight, s.ImageFormat)
+	} else {
+		p.logf("[!] Warning: Splash screen image format not recognized")
+	}
+	p.logf("[+] Splash screen Tcl/Tk libraries: %s, %s (%s)", s.TclLibName, s.TkLibName, s.TkLib)
+	p.logf("[+] Splash screen requires %d Tcl/Tk files", len(s.Requirements))
+
+	if pngData, err := s.PNG(); err == nil {
+		p.writeRawData(path.Join(dirName, "splash.png"), pngData)
+	} else {
+		p.logf("[!] Error: Failed to convert the splash image to PNG, extracting as is")
+		p.writeRawData(path.Join(dirName, "splash.img"), s.Image)
+	}
+	p.writeRawData(path.Join(dirName, "splash.tcl"), []byte(s.Script))
+	return nil
+}
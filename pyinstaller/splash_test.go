@@ -0,0 +1,127 @@
+package pyinstaller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+// buildSplash lays out splash resources after the given header names, with
+// the script, image and requirements sections in this order
+func buildSplash(names [][]byte, script string, img []byte, requirements []string) []byte {
+	var header []byte
+	for _, name := range names {
+		header = append(header, name...)
+	}
+	var reqs []byte
+	for _, name := range requirements {
+		reqs = append(append(reqs, name...), 0)
+	}
+	offset := len(header) + 6*4
+	for _, section := range [][]byte{[]byte(script), img, reqs} {
+		header = binary.BigEndian.AppendUint32(header, uint32(len(section)))
+		header = binary.BigEndian.AppendUint32(header, uint32(offset))
+		offset += len(section)
+	}
+	return append(append(append(header, script...), img...), reqs...)
+}
+
+// fixedString pads s with zeros to size bytes
+func fixedString(s string, size int) []byte {
+	b := make([]byte, size)
+	copy(b, s)
+	return b
+}
+
+func TestParseSplash(t *testing.T) {
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 3, 2)))
+	requirements := []string{"tcl/init.tcl", "tk/tk.tcl"}
+
+	splash6 := buildSplash([][]byte{fixedString("libtcl8.6.so", 32), fixedString("libtk8.6.so", 32), fixedString("tk", 16)},
+		"wm withdraw .", img.Bytes(), requirements)
+	splash5 := buildSplash([][]byte{fixedString("tcl86t.dll", 16), fixedString("tk86t.dll", 16), fixedString("tk", 16), fixedString("__splash", 16)},
+		"wm withdraw .", img.Bytes(), requirements)
+
+	tests := []struct {
+		name string
+		data []byte
+		want SplashScreen
+	}{
+		{"6.x", splash6, SplashScreen{TclLibName: "libtcl8.6.so", TkLibName: "libtk8.6.so", TkLib: "tk"}},
+		{"5.x", splash5, SplashScreen{TclLibName: "tcl86t.dll", TkLibName: "tk86t.dll", TkLib: "tk", RunDir: "__splash"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ParseSplash(test.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := test.want
+			want.Script = "wm withdraw ."
+			want.Image = img.Bytes()
+			want.ImageFormat, want.ImageWidth, want.ImageHeight = "png", 3, 2
+			want.Requirements = requirements
+			if !reflect.DeepEqual(*s, want) {
+				t.Errorf("ParseSplash = %+v, want %+v", *s, want)
+			}
+		})
+	}
+
+	t.Run("extract", func(t *testing.T) {
+		arch := openArchive(t, buildArchive([]testEntry{{"splash", 'l', splash6, true}}))
+		out := NewMemSink()
+		if err := arch.ExtractFiles(out); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := out.File("splash_extracted/splash.png"); !bytes.Equal(got, img.Bytes()) {
+			t.Errorf("splash.png = %q", got)
+		}
+		if got, _ := out.File("splash_extracted/splash.tcl"); string(got) != "wm withdraw ." {
+			t.Errorf("splash.tcl = %q", got)
+		}
+		if arch.Splash() == nil || arch.Splash().TkLib != "tk" {
+			t.Errorf("Splash() = %+v", arch.Splash())
+		}
+	})
+}
+
+func TestParseSplashMalformed(t *testing.T) {
+	valid := buildSplash([][]byte{make([]byte, 32), make([]byte, 32), make([]byte, 16)}, "script", []byte("image"), []string{"tk.tcl"})
+	// setField overwrites a uint32 of the 6.x header fields
+	setField := func(i int, v uint32) []byte {
+		data := append([]byte(nil), valid...)
+		binary.BigEndian.PutUint32(data[SPLASH6_HEADER_SIZE-24+4*i:], v)
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated header", valid[:SPLASH5_HEADER_SIZE-1]},
+		{"truncated data", valid[:len(valid)-1]},
+		{"offset in the header", setField(1, SPLASH6_HEADER_SIZE-4)},
+		{"offset past the end", setField(3, 0xffffffff)},
+		{"length past the end", setField(2, 0xfffffff0)},
+		// The script spans the image, the shorter requirements make the sizes
+		// add up
+		{"overlapping sections", func() []byte {
+			data := setField(0, uint32(len("script")+len("image")))
+			binary.BigEndian.PutUint32(data[SPLASH6_HEADER_SIZE-24+16:], uint32(len("tk.tcl\x00")-len("image")))
+			return data
+		}()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if s, err := ParseSplash(test.data); !errors.Is(err, ErrInvalidSplash) {
+				t.Errorf("ParseSplash = %+v, %v, want ErrInvalidSplash", s, err)
+			}
+		})
+	}
+}
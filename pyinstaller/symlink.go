@@ -0,0 +1,155 @@
+package pyinstaller
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SYMLINKS_MANIFEST lists the symbolic links of the CArchive when the sink
+// can't hold them, one "name -> target" line per link
+const SYMLINKS_MANIFEST = "symlinks.manifest"
+
+// Symlink is an ARCHIVE_ITEM_SYMLINK entry of the CArchive. Target is
+// relative to the directory of Name.
+type Symlink struct {
+	Name   string
+	Target string
+}
+
+// Symlinks returns the symbolic links met by ExtractFiles, including those
+// only recorded in SYMLINKS_MANIFEST.
+func (p *Archive) Symlinks() []Symlink {
+	return p.symlinks
+}
+
+// writeSymlink recreates a symbolic link in the sink, or records it for
+// SYMLINKS_MANIFEST when the sink can't hold it or the target lies outside
+// the extraction root.
+func (p *Archive) writeSymlink(name, target string) {
+	target, err := confineSymlink(name, target)
+	if err == nil {
+		err = p.checkSymlink(name, target)
+	}
+	link := Symlink{name, target}
+	p.symlinks = append(p.symlinks, link)
+	if err != nil {
+		p.logf("[!] Warning: Symlink %s: %v, recording it in %s", name, err, SYMLINKS_MANIFEST)
+		p.unwrittenSymlinks = append(p.unwrittenSymlinks, link)
+		return
+	}
+
+	if s, ok := p.out.(Symlinker); ok {
+		err := s.Symlink(target, name)
+		if err == nil {
+			p.links[path.Clean(name)] = target
+			p.recordFile(name)
+			return
+		}
+		if !errors.Is(err, errors.ErrUnsupported) {
+			p.logf("[!] Failed to create symlink %s, recording it in %s", name, SYMLINKS_MANIFEST)
+		}
+	}
+	p.unwrittenSymlinks = append(p.unwrittenSymlinks, link)
+}
+
+// writeSymlinksManifest writes the links which couldn't be created
+func (p *Archive) writeSymlinksManifest() {
+	if len(p.unwrittenSymlinks) == 0 {
+		return
+	}
+	var sb strings.Builder
+	for _, link := range p.unwrittenSymlinks {
+		fmt.Fprintf(&sb, "%s -> %s\n", link.Name, link.Target)
+	}
+	manifestPath := p.ensureUnique(SYMLINKS_MANIFEST, "")
+	p.writeRawData(manifestPath, []byte(sb.String()))
+	p.unwrittenSymlinks = nil
+}
+
+// confineSymlink normalizes the target of the link at name, relative to the
+// link's directory. Targets leaving the extraction root are rejected and
+// returned as found.
+func confineSymlink(name, target string) (string, error) {
+	target = strings.ReplaceAll(strings.Trim(target, "\x00"), "\\", "/")
+	if target == "" {
+		return target, errors.New("empty target")
+	}
+	if path.IsAbs(target) || len(target) >= 2 && target[1] == ':' {
+		return target, fmt.Errorf("absolute target %s", target)
+	}
+
+	dir := path.Dir(name)
+	resolved := path.Join(dir, target)
+	if resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return target, fmt.Errorf("target %s is outside the extraction directory", target)
+	}
+	rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(resolved))
+	if err != nil {
+		return target, err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// maxLinkExpansions bounds the links followed when resolving a path, like
+// the ELOOP limit of the kernel
+const maxLinkExpansions = 40
+
+// checkSymlink rejects links below another link and targets which leave the
+// extraction root once the links created so far are followed
+func (p *Archive) checkSymlink(name, target string) error {
+	if p.throughSymlink(name) {
+		return errors.New("link lies below another symlink")
+	}
+	if _, ok := p.resolveLinks(path.Dir(name) + "/" + target); !ok {
+		return fmt.Errorf("target %s is outside the extraction directory once links are followed", target)
+	}
+	return nil
+}
+
+// throughSymlink reports whether a parent directory of name is one of the
+// links created so far, which the sink would follow
+func (p *Archive) throughSymlink(name string) bool {
+	for dir := path.Dir(path.Clean(name)); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if _, ok := p.links[dir]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLinks resolves name, relative to the extraction root, through the
+// links created so far. ok is false if the path leaves the root or loops.
+func (p *Archive) resolveLinks(name string) (resolved string, ok bool) {
+	pending := strings.Split(name, "/")
+	var done []string
+	expansions := 0
+	for len(pending) > 0 {
+		part := pending[0]
+		pending = pending[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(done) == 0 {
+				return "", false
+			}
+			done = done[:len(done)-1]
+			continue
+		}
+		done = append(done, part)
+		target, isLink := p.links[strings.Join(done, "/")]
+		if !isLink {
+			continue
+		}
+		if expansions++; expansions > maxLinkExpansions {
+			return "", false
+		}
+		// The target is relative to the directory of the link
+		done = done[:len(done)-1]
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+	return strings.Join(done, "/"), true
+}
@@ -0,0 +1,96 @@
+package pyinstaller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// escapeEntries write through links which are confined on their own, along
+// with a framework whose links are fine
+var escapeEntries = []testEntry{
+	{"d1/l", 'n', []byte(".."), false},
+	{"d1/l/l2", 'n', []byte(".."), false},
+	{"d1/l/l2/pwned.txt", 'x', []byte("pwned"), false},
+	{"Fw/Versions/A/Python", 'x', []byte("lib"), false},
+	{"Fw/Versions/Current", 'n', []byte("A"), false},
+	{"Fw/Python", 'n', []byte("Versions/Current/Python"), false},
+}
+
+func TestSymlinkEscape(t *testing.T) {
+	data := buildArchive(escapeEntries)
+	parent := t.TempDir()
+	root := filepath.Join(parent, "out")
+	out, err := NewDirSink(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arch := openArchive(t, data)
+	if err := arch.ExtractFiles(out); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{filepath.Join(parent, "pwned.txt"), filepath.Join(root, "pwned.txt")} {
+		if _, err := os.Lstat(name); err == nil {
+			t.Errorf("%s was written", name)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "Fw", "Python")); err != nil || string(data) != "lib" {
+		t.Errorf("Fw/Python = %q, %v; want the framework library", data, err)
+	}
+	manifest, _ := os.ReadFile(filepath.Join(root, SYMLINKS_MANIFEST))
+	if string(manifest) != "d1/l/l2 -> ..\n" {
+		t.Errorf("manifest = %q", manifest)
+	}
+}
+
+func TestResolveLinks(t *testing.T) {
+	p := &Archive{links: map[string]string{
+		"d1/l":  "..",
+		"loop1": "loop2",
+		"loop2": "loop1",
+		"a":     "d1",
+	}}
+	tests := []struct {
+		name     string
+		resolved string
+		ok       bool
+	}{
+		{"d1/x", "d1/x", true},
+		{"d1/l/x", "x", true},
+		{"d1/l/..", "", false},
+		{"a/l/d1/l/y", "y", true},
+		{"loop1/x", "", false},
+		{"../x", "", false},
+	}
+	for _, test := range tests {
+		resolved, ok := p.resolveLinks(test.name)
+		if resolved != test.resolved || ok != test.ok {
+			t.Errorf("resolveLinks(%q) = %q, %v; want %q, %v", test.name, resolved, ok, test.resolved, test.ok)
+		}
+	}
+}
+
+func TestDirSinkRefusesSymlinks(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "out")
+	out, err := NewDirSink(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..", filepath.Join(root, "up")); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+	if err := out.WriteFile("up/pwned.txt", []byte("pwned")); err == nil {
+		t.Error("WriteFile wrote through a symlink")
+	}
+	if err := out.Symlink("..", "up/l"); err == nil {
+		t.Error("Symlink created a link through a symlink")
+	}
+	if err := out.WriteFile("up", []byte("pwned")); err == nil {
+		t.Error("WriteFile overwrote a symlink")
+	}
+	if _, err := os.Lstat(filepath.Join(parent, "pwned.txt")); err == nil {
+		t.Error("pwned.txt was written outside the root")
+	}
+}
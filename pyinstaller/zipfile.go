@@ -0,0 +1,98 @@
+package pyinstaller
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// BASE_LIBRARY_ZIP holds the part of the standard library needed to start
+// the interpreter
+const BASE_LIBRARY_ZIP = "base_library.zip"
+
+// maxZipDepth limits the nesting of zip files within zip files
+const maxZipDepth = 4
+
+// Limits on the decompressed size of zip members, which could otherwise be
+// zip bombs
+const (
+	maxZipMemberSize = 256 << 20
+	maxZipTotalSize  = 1 << 30 // For all the zip files of an extraction
+)
+
+// isEmbeddedZip reports whether a file should be unpacked by extractZip
+func isEmbeddedZip(name string) bool {
+	return path.Base(name) == BASE_LIBRARY_ZIP
+}
+
+// extractZip unpacks a zip file into <zipPath>_extracted, recursing into
+// nested zip files. The header of every pyc is checked against the Python
+// version of the archive.
+func (p *Archive) extractZip(zipPath string, zipData []byte, depth int) error {
+	zr, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return err
+	}
+	dirName := zipPath + "_extracted"
+	p.logf("[+] Found %d files in %s", len(zr.File), zipPath)
+
+	mismatched := 0
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		size := f.UncompressedSize64
+		if size > maxZipMemberSize || size > uint64(maxZipTotalSize-p.unzippedSize) {
+			p.logf("[!] Warning: %s in %s is too large to unpack (%d bytes), skipping", f.Name, zipPath, size)
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			p.logf("[!] Error: Failed to open %s in %s: %v", f.Name, zipPath, err)
+			continue
+		}
+		// Read one byte past the declared size to catch lying headers
+		data, err := io.ReadAll(io.LimitReader(rc, int64(size)+1))
+		rc.Close()
+		if err == nil && uint64(len(data)) > size {
+			err = errors.New("larger than its declared size")
+		}
+		if err != nil {
+			p.logf("[!] Error: Failed to decompress %s in %s: %v", f.Name, zipPath, err)
+			continue
+		}
+		p.unzippedSize += int64(len(data))
+
+		memberPath := p.ensureUnique(path.Join(dirName, sanitizePath(f.Name)), "")
+		p.writeRawData(memberPath, data)
+
+		switch {
+		case strings.HasSuffix(f.Name, ".pyc"):
+			major, minor, ok := pycVersion(data)
+			if !ok {
+				p.logf("[!] Warning: %s has an invalid pyc header", memberPath)
+			} else if major != p.pythonMajorVersion || minor != p.pythonMinorVersion {
+				mismatched++
+			} else {
+				if !p.gotPycMagic {
+					copy(p.pycMagic[:], data[0:4])
+					p.gotPycMagic = true
+				}
+				p.writeDisassembly(memberPath, data)
+			}
+		case strings.HasSuffix(f.Name, ".zip"):
+			if depth+1 >= maxZipDepth {
+				p.logf("[!] Warning: %s is nested too deep, not unpacking it", memberPath)
+			} else if err := p.extractZip(memberPath, data, depth+1); err != nil {
+				p.logf("[!] Failed to extract zip %s: %v", memberPath, err)
+			}
+		}
+	}
+	if mismatched != 0 {
+		p.logf("[!] Warning: %d pyc files in %s weren't compiled by Python %d.%d", mismatched, zipPath, p.pythonMajorVersion, p.pythonMinorVersion)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package pyinstaller
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"testing"
+)
+
+// zipBombs returns a zip file holding ok.txt and two members of 1 MB of
+// zeros, one declared too large to unpack and one declared 10 bytes long
+func zipBombs(t testing.TB) []byte {
+	var compressed bytes.Buffer
+	fw, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	fw.Write(make([]byte, 1<<20))
+	fw.Close()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("ok.txt")
+	w.Write([]byte("fine"))
+	// Sizes are taken from the headers, which the bombs lie about
+	for _, member := range []struct {
+		name string
+		size uint64
+	}{
+		{"huge.bin", maxZipMemberSize + 1},
+		{"lying.bin", 10},
+	} {
+		w, err := zw.CreateRaw(&zip.FileHeader{
+			Name:               member.name,
+			Method:             zip.Deflate,
+			CompressedSize64:   uint64(compressed.Len()),
+			UncompressedSize64: member.size,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(compressed.Bytes())
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestExtractZipLimits(t *testing.T) {
+	arch := openArchive(t, buildArchive([]testEntry{{"data.zip", 'Z', zipBombs(t), false}}))
+	out := NewMemSink()
+	if err := arch.ExtractFiles(out); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := out.File("data.zip_extracted/ok.txt"); !ok || string(data) != "fine" {
+		t.Errorf("ok.txt = %q, %v", data, ok)
+	}
+	for _, name := range []string{"data.zip_extracted/huge.bin", "data.zip_extracted/lying.bin"} {
+		if _, ok := out.File(name); ok {
+			t.Errorf("%s was unpacked", name)
+		}
+	}
+}